@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// AccessLogFormat is the format of the access log lines.
+type AccessLogFormat int
+
+const (
+	// CombinedLogFormat is the NCSA combined log format:
+	// host ident authuser [date] "request" status bytes "referer" "user-agent"
+	CombinedLogFormat AccessLogFormat = iota
+	// CommonLogFormat is the NCSA common log format: host ident authuser [date] "request" status bytes
+	CommonLogFormat
+)
+
+const accessLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// ConfigAccessLog defines the Apache/NCSA access log written by the HTTP middlewares, independent
+// of the request log.
+type ConfigAccessLog struct {
+	// Output is the writer of the access log. The access log is disabled when it is nil.
+	Output io.Writer
+
+	// Format is the format of the access log lines. Default is CombinedLogFormat.
+	Format AccessLogFormat
+
+	// Latency appends the latency of the request in seconds to the line.
+	Latency bool
+
+	// RequestIDHeader appends the value of this request header, quoted, to the line.
+	RequestIDHeader string
+}
+
+// accessLogger writes access log lines, one Write call per line.
+type accessLogger struct {
+	sync.Mutex
+	config ConfigAccessLog
+}
+
+// accessLogEntry holds the values of one access log line.
+type accessLogEntry struct {
+	host      string
+	user      string
+	time      time.Time
+	method    string
+	uri       string
+	proto     string
+	status    int
+	size      int64
+	referer   string
+	userAgent string
+	requestID string
+	latency   time.Duration
+}
+
+// newAccessLogger returns nil when the access log is disabled.
+func newAccessLogger(config ConfigAccessLog) *accessLogger {
+	if config.Output == nil {
+		return nil
+	}
+	return &accessLogger{config: config}
+}
+
+func (a *accessLogger) write(e accessLogEntry) {
+	b := &bytes.Buffer{}
+	b.WriteString(accessLogValue(e.host))
+	b.WriteString(" - ")
+	b.WriteString(accessLogValue(e.user))
+	b.WriteString(" [")
+	b.WriteString(e.time.Format(accessLogTimeFormat))
+	b.WriteString("] \"")
+	b.WriteString(escapeAccessLog(e.method + " " + e.uri + " " + e.proto))
+	b.WriteString("\" ")
+	b.WriteString(strconv.Itoa(e.status))
+	b.WriteByte(' ')
+	if e.size > 0 {
+		b.WriteString(strconv.FormatInt(e.size, 10))
+	} else {
+		b.WriteByte('-')
+	}
+	if a.config.Format == CombinedLogFormat {
+		b.WriteString(" \"")
+		b.WriteString(escapeAccessLog(e.referer))
+		b.WriteString("\" \"")
+		b.WriteString(escapeAccessLog(e.userAgent))
+		b.WriteByte('"')
+	}
+	if a.config.Latency {
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatFloat(e.latency.Seconds(), 'f', 6, 64))
+	}
+	if a.config.RequestIDHeader != "" {
+		b.WriteString(" \"")
+		b.WriteString(escapeAccessLog(e.requestID))
+		b.WriteByte('"')
+	}
+	b.WriteByte('\n')
+
+	a.Lock()
+	defer a.Unlock()
+	_, _ = a.config.Output.Write(b.Bytes())
+}
+
+// accessLogValue returns "-" for an empty unquoted value.
+func accessLogValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return escapeAccessLog(s)
+}
+
+// escapeAccessLog escapes quotes, backslashes and non-printable characters the way Apache does.
+func escapeAccessLog(s string) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"' || r == '\\':
+			b = append(b, '\\', byte(r))
+		case r == utf8.RuneError && size == 1, r < ' ', r == 0x7f:
+			b = append(b, `\x`...)
+			b = append(b, "0123456789abcdef"[s[i]>>4], "0123456789abcdef"[s[i]&0xf])
+		default:
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return string(b)
+}
+
+// basicAuthUser returns the user name of a basic Authorization header value.
+func basicAuthUser(authorization string) string {
+	const prefix = "Basic "
+	if len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return ""
+	}
+	decoded, err := base64.StdEncoding.DecodeString(authorization[len(prefix):])
+	if err != nil {
+		return ""
+	}
+	user, _, _ := strings.Cut(string(decoded), ":")
+	return user
+}
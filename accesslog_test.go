@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestAccessLoggerWrite(t *testing.T) {
+	entry := accessLogEntry{
+		host:      "10.0.0.1",
+		user:      "frank",
+		time:      time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		method:    "GET",
+		uri:       "/apache_pb.gif",
+		proto:     "HTTP/1.0",
+		status:    200,
+		size:      2326,
+		referer:   "http://www.example.com/start.html",
+		userAgent: `Mozilla/4.08 "test"`,
+		requestID: "r1",
+		latency:   1500 * time.Millisecond,
+	}
+	tests := []struct {
+		name   string
+		config ConfigAccessLog
+		entry  accessLogEntry
+		expect string
+	}{
+		{
+			name:   "Test common log format",
+			config: ConfigAccessLog{Format: CommonLogFormat},
+			entry:  entry,
+			expect: `10.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326` + "\n",
+		},
+		{
+			name:   "Test combined log format",
+			config: ConfigAccessLog{},
+			entry:  entry,
+			expect: `10.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 \"test\""` + "\n",
+		},
+		{
+			name:   "Test extended fields",
+			config: ConfigAccessLog{Format: CommonLogFormat, Latency: true, RequestIDHeader: "X-Request-Id"},
+			entry:  entry,
+			expect: `10.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 1.500000 "r1"` + "\n",
+		},
+		{
+			name:   "Test empty values",
+			config: ConfigAccessLog{Format: CommonLogFormat},
+			entry:  accessLogEntry{time: entry.time, method: "GET", uri: "/\x01", proto: "HTTP/1.1", status: 204},
+			expect: `- - - [10/Oct/2000:13:55:36 -0700] "GET /\x01 HTTP/1.1" 204 -` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			tt.config.Output = buf
+			newAccessLogger(tt.config).write(tt.entry)
+			assert.Equal(t, tt.expect, buf.String())
+		})
+	}
+}
+
+func TestBasicAuthUser(t *testing.T) {
+	assert.Equal(t, "frank", basicAuthUser("Basic ZnJhbms6c2VjcmV0"))
+	assert.Equal(t, "", basicAuthUser("Bearer token"))
+	assert.Equal(t, "", basicAuthUser("Basic !!!"))
+}
+
+func TestAccessLogMiddlewares(t *testing.T) {
+	var (
+		pattern = regexp.MustCompile(`^192\.0\.2\.1 - frank \[[^\]]+\] "GET /hello\?page=1 HTTP/1\.1" 200 5 "http://example\.com" "curl/7\.64\.1" [0-9]+\.[0-9]{6} "r1"\n$`)
+		newReq  = func() *http.Request {
+			req := httptest.NewRequest("GET", "/hello?page=1", nil)
+			req.SetBasicAuth("frank", "secret")
+			req.Header.Set("Referer", "http://example.com")
+			req.Header.Set("User-Agent", "curl/7.64.1")
+			req.Header.Set("X-Request-Id", "r1")
+			return req
+		}
+		newConfig = func(buf *bytes.Buffer) ConfigAccessLog {
+			return ConfigAccessLog{Output: buf, Latency: true, RequestIDHeader: "X-Request-Id"}
+		}
+	)
+
+	t.Run("Gin", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(&bytes.Buffer{}))
+		server := gin.New()
+		server.Use(GinMiddleware(ConfigGin{AccessLog: newConfig(buf)}))
+		server.GET("/hello", func(ctx *gin.Context) {
+			ctx.String(200, "hello")
+		})
+		server.ServeHTTP(httptest.NewRecorder(), newReq())
+		assert.Regexp(t, pattern, buf.String())
+	})
+
+	t.Run("Echo", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(&bytes.Buffer{}))
+		server := echo.New()
+		server.Use(EchoMiddleware(ConfigEcho{AccessLog: newConfig(buf)}))
+		server.GET("/hello", func(ctx echo.Context) error {
+			return ctx.String(200, "hello")
+		})
+		server.ServeHTTP(httptest.NewRecorder(), newReq())
+		assert.Regexp(t, pattern, buf.String())
+	})
+
+	t.Run("Fiber", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(&bytes.Buffer{}))
+		server := fiber.New()
+		server.Use(FiberMiddleware(ConfigFiber{AccessLog: newConfig(buf)}))
+		server.Get("/hello", func(ctx *fiber.Ctx) error {
+			return ctx.SendString("hello")
+		})
+		_, err := server.Test(newReq())
+		assert.Nil(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^0\.0\.0\.0 - frank .* "r1"\n$`), buf.String())
+	})
+}
@@ -0,0 +1,258 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// OverflowPolicy defines what an AsyncWriter does with a line written when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the writer until a line is written.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the line written.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest line of the buffer, or the line written when every
+	// buffered line is being written.
+	OverflowDropOldest
+	// OverflowSample keeps one line in SampleRate, dropping the oldest line of the buffer, and drops the others.
+	// Every line is dropped while every buffered line is being written.
+	OverflowSample
+)
+
+const (
+	// DefaultAsyncBufferSize is the default number of lines buffered by an AsyncWriter.
+	DefaultAsyncBufferSize = 1024
+	// DefaultSampleRate is the default rate of the lines kept by OverflowSample.
+	DefaultSampleRate = 10
+)
+
+// ErrAsyncWriterClosed is returned by the writes of a closed AsyncWriter.
+var ErrAsyncWriterClosed = errors.New("logger: async writer closed")
+
+// ConfigAsync defines the buffer of an AsyncWriter.
+type ConfigAsync struct {
+	// Size is the number of lines buffered, including the lines being written to the output.
+	// Default is DefaultAsyncBufferSize.
+	Size int
+
+	// Policy is what is done with the lines written when the buffer is full. Default is OverflowBlock.
+	Policy OverflowPolicy
+
+	// SampleRate keeps one line in SampleRate with OverflowSample. Default is DefaultSampleRate.
+	SampleRate int
+
+	// Keep reports whether a line must be kept by OverflowSample. Default keeps the lines of the
+	// requests forced to debug.
+	Keep func(line []byte) bool
+}
+
+// AsyncStats are the counters of an AsyncWriter.
+type AsyncStats struct {
+	// Written is the number of lines written to the output.
+	Written uint64 `json:"written"`
+	// Dropped is the number of lines dropped by OverflowDropNewest and OverflowDropOldest.
+	Dropped uint64 `json:"dropped"`
+	// Sampled is the number of lines dropped by OverflowSample.
+	Sampled uint64 `json:"sampled"`
+	// Errors is the number of lines which failed to be written to the output.
+	Errors uint64 `json:"errors"`
+}
+
+// AsyncWriter writes the lines to its output in a goroutine, so a slow output does not stall the
+// requests. Every Write is a line, it is copied to a bounded ring buffer and counted as buffered
+// until it is written to the output. Use it with WithOutput
+// and Flush or Close it on shutdown. It is safe for concurrent use.
+type AsyncWriter struct {
+	out    io.Writer
+	config ConfigAsync
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	lines    [][]byte
+	head     int
+	count    int
+	writing  int
+	closed   bool
+	overflow int
+	drained  []chan struct{}
+	stats    AsyncStats
+	done     chan struct{}
+}
+
+// NewAsyncWriter returns an AsyncWriter writing to out.
+func NewAsyncWriter(out io.Writer, config ConfigAsync) *AsyncWriter {
+	if config.Size <= 0 {
+		config.Size = DefaultAsyncBufferSize
+	}
+	if config.SampleRate <= 0 {
+		config.SampleRate = DefaultSampleRate
+	}
+	if config.Keep == nil {
+		config.Keep = isDebugLine
+	}
+	w := &AsyncWriter{
+		out:    out,
+		config: config,
+		lines:  make([][]byte, config.Size),
+		done:   make(chan struct{}),
+	}
+	w.notEmpty = sync.NewCond(&w.mu)
+	w.notFull = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// Write buffers a copy of p. It never fails, except when the writer is closed.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.full() && w.config.Policy == OverflowBlock && !w.closed {
+		w.notFull.Wait()
+	}
+	if w.closed {
+		return 0, ErrAsyncWriterClosed
+	}
+	if w.full() {
+		switch w.config.Policy {
+		case OverflowDropNewest:
+			w.stats.Dropped++
+			return len(p), nil
+		case OverflowDropOldest:
+			w.stats.Dropped++
+			if w.count == 0 {
+				return len(p), nil
+			}
+			w.pop()
+		case OverflowSample:
+			// Either the line or the oldest line of the buffer is dropped.
+			w.stats.Sampled++
+			w.overflow++
+			if (w.overflow-1)%w.config.SampleRate != 0 && !w.config.Keep(line) || w.count == 0 {
+				return len(p), nil
+			}
+			w.pop()
+		}
+	} else {
+		w.overflow = 0
+	}
+	w.lines[(w.head+w.count)%len(w.lines)] = line
+	w.count++
+	w.notEmpty.Signal()
+	return len(p), nil
+}
+
+// full reports whether the buffer is full, the lines being written to the output included.
+func (w *AsyncWriter) full() bool {
+	return w.count+w.writing >= len(w.lines)
+}
+
+// pop drops the oldest line of the buffer.
+func (w *AsyncWriter) pop() {
+	w.lines[w.head] = nil
+	w.head = (w.head + 1) % len(w.lines)
+	w.count--
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for {
+		w.mu.Lock()
+		for w.count == 0 && !w.closed {
+			w.notEmpty.Wait()
+		}
+		if w.count == 0 {
+			w.mu.Unlock()
+			return
+		}
+		batch := make([][]byte, w.count)
+		for i := range batch {
+			batch[i] = w.lines[w.head]
+			w.pop()
+		}
+		// The lines are buffered until they are written, the writes wait for the batch.
+		w.writing = len(batch)
+		w.mu.Unlock()
+
+		var written, failed uint64
+		for _, line := range batch {
+			if _, err := w.out.Write(line); err != nil {
+				failed++
+			} else {
+				written++
+			}
+		}
+
+		w.mu.Lock()
+		w.writing = 0
+		w.stats.Written += written
+		w.stats.Errors += failed
+		w.notFull.Broadcast()
+		if w.count == 0 {
+			for _, drained := range w.drained {
+				close(drained)
+			}
+			w.drained = nil
+		}
+		w.mu.Unlock()
+	}
+}
+
+// Flush waits until the buffered lines are written to the output or ctx is done.
+func (w *AsyncWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	if w.count == 0 && w.writing == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	drained := make(chan struct{})
+	w.drained = append(w.drained, drained)
+	w.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes the buffered lines to the output and stops the writer. The next writes fail with
+// ErrAsyncWriterClosed. The output is not closed.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	w.closed = true
+	w.notEmpty.Broadcast()
+	w.notFull.Broadcast()
+	w.mu.Unlock()
+	<-w.done
+	return nil
+}
+
+// Stats returns the counters of the writer.
+func (w *AsyncWriter) Stats() AsyncStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+// DropCounts implements DropCounter.
+func (w *AsyncWriter) DropCounts() (dropped, sampled uint64) {
+	stats := w.Stats()
+	return stats.Dropped, stats.Sampled
+}
+
+// isDebugLine reports whether a JSON, logfmt or console line is the log of a request forced to debug.
+func isDebugLine(line []byte) bool {
+	return bytes.Contains(line, []byte(`"`+DebugField+`":true`)) || bytes.Contains(line, []byte(" "+DebugField+"=true"))
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter blocks the writes until it is released.
+type blockingWriter struct {
+	sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{release: make(chan struct{})}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.Lock()
+	defer w.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) lines() []string {
+	w.Lock()
+	defer w.Unlock()
+	return strings.Split(strings.TrimSpace(w.buf.String()), "\n")
+}
+
+// fillAsyncWriter writes n lines while the output is blocked, the first line being taken by the
+// goroutine of the writer.
+func fillAsyncWriter(t *testing.T, w *AsyncWriter, lines ...string) {
+	for i, line := range lines {
+		_, err := w.Write([]byte(line + "\n"))
+		assert.Nil(t, err)
+		if i == 0 {
+			assert.Eventually(t, func() bool {
+				w.mu.Lock()
+				defer w.mu.Unlock()
+				return w.writing > 0
+			}, time.Second, time.Millisecond)
+		}
+	}
+}
+
+func TestAsyncWriter(t *testing.T) {
+	buf := &blockingWriter{release: make(chan struct{})}
+	close(buf.release)
+	w := NewAsyncWriter(buf, ConfigAsync{})
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	assert.Nil(t, w.Flush(context.Background()))
+	lines := buf.lines()
+	assert.Len(t, lines, 100)
+	assert.Equal(t, "line 99", lines[99])
+	assert.Equal(t, AsyncStats{Written: 100}, w.Stats())
+
+	assert.Nil(t, w.Close())
+	_, err := w.Write([]byte("closed\n"))
+	assert.Equal(t, ErrAsyncWriterClosed, err)
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ConfigAsync
+		lines   []string
+		expect  []string
+		dropped uint64
+		sampled uint64
+	}{
+		{
+			name:    "Test drop newest",
+			config:  ConfigAsync{Size: 3, Policy: OverflowDropNewest},
+			lines:   []string{"writing", "a", "b", "c", "d"},
+			expect:  []string{"writing", "a", "b"},
+			dropped: 2,
+		},
+		{
+			name:    "Test drop oldest",
+			config:  ConfigAsync{Size: 3, Policy: OverflowDropOldest},
+			lines:   []string{"writing", "a", "b", "c", "d"},
+			expect:  []string{"writing", "c", "d"},
+			dropped: 2,
+		},
+		{
+			name:    "Test sample",
+			config:  ConfigAsync{Size: 3, Policy: OverflowSample, SampleRate: 2},
+			lines:   []string{"writing", "a", "b", "c", "d", "e", `{"debug":true}`},
+			expect:  []string{"writing", "e", `{"debug":true}`},
+			sampled: 4,
+		},
+		{
+			name:    "Test drop oldest of the lines being written",
+			config:  ConfigAsync{Size: 1, Policy: OverflowDropOldest},
+			lines:   []string{"writing", "a", "b"},
+			expect:  []string{"writing"},
+			dropped: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := newBlockingWriter()
+			w := NewAsyncWriter(buf, tt.config)
+			fillAsyncWriter(t, w, tt.lines...)
+			close(buf.release)
+			assert.Nil(t, w.Close())
+			assert.Equal(t, tt.expect, buf.lines())
+			stats := w.Stats()
+			assert.Equal(t, tt.dropped, stats.Dropped)
+			assert.Equal(t, tt.sampled, stats.Sampled)
+			assert.Equal(t, uint64(len(tt.expect)), stats.Written)
+		})
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	buf := newBlockingWriter()
+	w := NewAsyncWriter(buf, ConfigAsync{Size: 2})
+	fillAsyncWriter(t, w, "writing", "a")
+
+	written := make(chan struct{})
+	go func() {
+		fmt.Fprintln(w, "b")
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Error("write must block while the buffer is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, w.Flush(ctx))
+
+	close(buf.release)
+	<-written
+	assert.Nil(t, w.Flush(context.Background()))
+	assert.Equal(t, []string{"writing", "a", "b"}, buf.lines())
+	assert.Nil(t, w.Close())
+}
+
+func TestAsyncWriterMiddleware(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewAsyncWriter(buf, ConfigAsync{})
+	New(WithOutput(w))
+	defer func() {
+		New(WithOutput(os.Stderr))
+		w.Close()
+	}()
+	server := gin.New()
+	server.Use(GinMiddleware(ConfigGin{Formatter: &JSONFormatter{}}))
+	server.GET("/hello", func(ctx *gin.Context) {
+		GetLogger(ctx).AddLog("async")
+	})
+	performRequest(server, "GET", "/hello")
+	assert.Nil(t, w.Flush(context.Background()))
+	data := decodeLog(t, buf)
+	assert.Equal(t, "async", data["STEP_1"])
+	assert.Equal(t, "/hello", data[RouteField])
+	assert.Equal(t, AsyncStats{Written: 1}, w.Stats())
+}
@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"strings"
+)
+
+const (
+	RequestHeadersField  = "request_headers"
+	ResponseHeadersField = "response_headers"
+	QueryParamsField     = "query_params"
+
+	// RedactedValue replaces the value of a redacted header or query parameter.
+	RedactedValue = "[REDACTED]"
+	// CaptureAll in an allowlist captures every header or query parameter.
+	CaptureAll = "*"
+)
+
+// DefaultRedactHeaders is the list of headers redacted when ConfigCapture.RedactHeaders is nil.
+var DefaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// ConfigCapture defines which request/response headers and query parameters are added to the
+// request log. Header names are matched case-insensitively. For gRPC the request headers are the
+// incoming metadata, response headers and query parameters are not used.
+type ConfigCapture struct {
+	// RequestHeaders is the allowlist of request headers to log. CaptureAll logs every header.
+	RequestHeaders []string
+
+	// ResponseHeaders is the allowlist of response headers to log. CaptureAll logs every header.
+	ResponseHeaders []string
+
+	// QueryParams is the allowlist of query parameters to log. CaptureAll logs every parameter.
+	QueryParams []string
+
+	// DenyHeaders is the list of headers which are never logged, even when allowed.
+	DenyHeaders []string
+
+	// DenyQueryParams is the list of query parameters which are never logged, even when allowed.
+	DenyQueryParams []string
+
+	// RedactHeaders is the list of headers logged with RedactedValue instead of their value.
+	// DefaultRedactHeaders is used when it is nil.
+	RedactHeaders []string
+
+	// RedactQueryParams is the list of query parameters logged with RedactedValue instead of their value.
+	RedactQueryParams []string
+
+	// HeaderFields maps a header name to a top-level log field, instead of logging it under
+	// RequestHeadersField or ResponseHeadersField.
+	HeaderFields map[string]string
+
+	// QueryFields maps a query parameter name to a top-level log field, instead of logging it under
+	// QueryParamsField.
+	QueryFields map[string]string
+
+	// RequestHeadersField is the log field of request headers. Default is the field of the Schema.
+	RequestHeadersField string
+
+	// ResponseHeadersField is the log field of response headers. Default is the field of the Schema.
+	ResponseHeadersField string
+
+	// QueryParamsField is the log field of query parameters. Default is the field of the Schema.
+	QueryParamsField string
+}
+
+// capturer is the compiled form of ConfigCapture used by the middlewares.
+type capturer struct {
+	requestHeaders  *nameSet
+	responseHeaders *nameSet
+	queryParams     *nameSet
+	denyHeaders     *nameSet
+	denyQuery       *nameSet
+	redactHeaders   *nameSet
+	redactQuery     *nameSet
+	headerFields    map[string]string
+	queryFields     map[string]string
+
+	requestHeadersField  string
+	responseHeadersField string
+	queryParamsField     string
+}
+
+// nameSet is a case-insensitive set of names.
+type nameSet struct {
+	all   bool
+	names map[string]struct{}
+}
+
+func newNameSet(names []string) *nameSet {
+	if len(names) == 0 {
+		return nil
+	}
+	set := &nameSet{names: make(map[string]struct{}, len(names))}
+	for _, name := range names {
+		if name == CaptureAll {
+			set.all = true
+			continue
+		}
+		set.names[strings.ToLower(name)] = struct{}{}
+	}
+	return set
+}
+
+func (s *nameSet) has(name string) bool {
+	if s == nil {
+		return false
+	}
+	if s.all {
+		return true
+	}
+	_, ok := s.names[strings.ToLower(name)]
+	return ok
+}
+
+func lowerKeys(fields map[string]string) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	lowered := make(map[string]string, len(fields))
+	for k, v := range fields {
+		lowered[strings.ToLower(k)] = v
+	}
+	return lowered
+}
+
+// newCapturer returns nil when nothing has to be captured, so the middlewares can skip
+// collecting headers entirely.
+func newCapturer(config ConfigCapture, schema Schema) *capturer {
+	if len(config.RequestHeaders) == 0 && len(config.ResponseHeaders) == 0 && len(config.QueryParams) == 0 {
+		return nil
+	}
+	if config.RedactHeaders == nil {
+		config.RedactHeaders = DefaultRedactHeaders
+	}
+	if config.RequestHeadersField == "" {
+		config.RequestHeadersField = schema.RequestHeaders
+	}
+	if config.ResponseHeadersField == "" {
+		config.ResponseHeadersField = schema.ResponseHeaders
+	}
+	if config.QueryParamsField == "" {
+		config.QueryParamsField = schema.QueryParams
+	}
+	return &capturer{
+		requestHeaders:       newNameSet(config.RequestHeaders),
+		responseHeaders:      newNameSet(config.ResponseHeaders),
+		queryParams:          newNameSet(config.QueryParams),
+		denyHeaders:          newNameSet(config.DenyHeaders),
+		denyQuery:            newNameSet(config.DenyQueryParams),
+		redactHeaders:        newNameSet(config.RedactHeaders),
+		redactQuery:          newNameSet(config.RedactQueryParams),
+		headerFields:         lowerKeys(config.HeaderFields),
+		queryFields:          lowerKeys(config.QueryFields),
+		requestHeadersField:  config.RequestHeadersField,
+		responseHeadersField: config.ResponseHeadersField,
+		queryParamsField:     config.QueryParamsField,
+	}
+}
+
+// filter returns the values allowed by allow and not denied by deny, keyed by their log field.
+// Values mapped by renames are added to fields as top-level fields.
+func filter(values map[string][]string, allow, deny, redact *nameSet, renames map[string]string, fields map[string]interface{}) map[string]interface{} {
+	if allow == nil || len(values) == 0 {
+		return nil
+	}
+	captured := make(map[string]interface{})
+	for name, value := range values {
+		if !allow.has(name) || deny.has(name) {
+			continue
+		}
+		v := strings.Join(value, ", ")
+		if redact.has(name) {
+			v = RedactedValue
+		}
+		if field, ok := renames[strings.ToLower(name)]; ok {
+			fields[field] = v
+			continue
+		}
+		captured[name] = v
+	}
+	if len(captured) == 0 {
+		return nil
+	}
+	return captured
+}
+
+// requestFields returns the log fields for the request headers and query parameters.
+func (c *capturer) requestFields(headers, query map[string][]string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if captured := filter(headers, c.requestHeaders, c.denyHeaders, c.redactHeaders, c.headerFields, fields); captured != nil {
+		fields[c.requestHeadersField] = captured
+	}
+	if captured := filter(query, c.queryParams, c.denyQuery, c.redactQuery, c.queryFields, fields); captured != nil {
+		fields[c.queryParamsField] = captured
+	}
+	return fields
+}
+
+// responseFields returns the log fields for the response headers.
+func (c *capturer) responseFields(headers map[string][]string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if captured := filter(headers, c.responseHeaders, c.denyHeaders, c.redactHeaders, c.headerFields, fields); captured != nil {
+		fields[c.responseHeadersField] = captured
+	}
+	return fields
+}
+
+// visitValues collects the key/values of a fasthttp VisitAll function.
+func visitValues(visitAll func(func(key, value []byte))) map[string][]string {
+	values := make(map[string][]string)
+	visitAll(func(key, value []byte) {
+		k := string(key)
+		values[k] = append(values[k], string(value))
+	})
+	return values
+}
@@ -0,0 +1,187 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	pb "github.com/trinhdaiphuc/logger/proto/hello"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCapturerRequestFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ConfigCapture
+		headers map[string][]string
+		query   map[string][]string
+		expect  map[string]interface{}
+	}{
+		{
+			name:    "Test empty config capture nothing",
+			config:  ConfigCapture{},
+			headers: map[string][]string{"X-Tenant-Id": {"t1"}},
+			expect:  nil,
+		},
+		{
+			name:   "Test allowlist and query params",
+			config: ConfigCapture{RequestHeaders: []string{"x-tenant-id"}, QueryParams: []string{"page"}},
+			headers: map[string][]string{
+				"X-Tenant-Id": {"t1"},
+				"Accept":      {"*/*"},
+			},
+			query: map[string][]string{"page": {"1", "2"}, "size": {"10"}},
+			expect: map[string]interface{}{
+				RequestHeadersField: map[string]interface{}{"X-Tenant-Id": "t1"},
+				QueryParamsField:    map[string]interface{}{"page": "1, 2"},
+			},
+		},
+		{
+			name:   "Test capture all with deny and default redaction",
+			config: ConfigCapture{RequestHeaders: []string{CaptureAll}, DenyHeaders: []string{"Accept"}},
+			headers: map[string][]string{
+				"Authorization": {"Bearer token"},
+				"Accept":        {"*/*"},
+			},
+			expect: map[string]interface{}{
+				RequestHeadersField: map[string]interface{}{"Authorization": RedactedValue},
+			},
+		},
+		{
+			name: "Test custom field names",
+			config: ConfigCapture{
+				RequestHeaders:      []string{"X-Forwarded-For", "X-Tenant-Id"},
+				QueryParams:         []string{"token"},
+				RedactQueryParams:   []string{"token"},
+				HeaderFields:        map[string]string{"X-Forwarded-For": "forwarded_for"},
+				RequestHeadersField: "headers",
+				QueryParamsField:    "query",
+			},
+			headers: map[string][]string{
+				"X-Forwarded-For": {"10.0.0.1"},
+				"X-Tenant-Id":     {"t1"},
+			},
+			query: map[string][]string{"token": {"secret"}},
+			expect: map[string]interface{}{
+				"forwarded_for": "10.0.0.1",
+				"headers":       map[string]interface{}{"X-Tenant-Id": "t1"},
+				"query":         map[string]interface{}{"token": RedactedValue},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCapturer(tt.config, DefaultSchema)
+			if tt.expect == nil {
+				assert.Nil(t, c)
+				return
+			}
+			assert.Equal(t, tt.expect, c.requestFields(tt.headers, tt.query))
+		})
+	}
+}
+
+func TestCaptureMiddlewares(t *testing.T) {
+	capture := ConfigCapture{
+		RequestHeaders:  []string{"X-Tenant-Id", "Cookie"},
+		ResponseHeaders: []string{"X-Request-Id"},
+		QueryParams:     []string{"page"},
+	}
+	tests := []struct {
+		name    string
+		handler func() http.Handler
+	}{
+		{
+			name: "Gin",
+			handler: func() http.Handler {
+				server := gin.New()
+				server.Use(GinMiddleware(ConfigGin{Capture: capture}))
+				server.GET("/hello", func(ctx *gin.Context) {
+					ctx.Header("X-Request-Id", "r1")
+					ctx.String(200, "hello")
+				})
+				return server
+			},
+		},
+		{
+			name: "Echo",
+			handler: func() http.Handler {
+				server := echo.New()
+				server.Use(EchoMiddleware(ConfigEcho{Capture: capture}))
+				server.GET("/hello", func(ctx echo.Context) error {
+					ctx.Response().Header().Set("X-Request-Id", "r1")
+					return ctx.String(200, "hello")
+				})
+				return server
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+			req := httptest.NewRequest("GET", "/hello?page=2&size=10", nil)
+			req.Header.Set("X-Tenant-Id", "t1")
+			req.Header.Set("Cookie", "session=secret")
+			tt.handler().ServeHTTP(httptest.NewRecorder(), req)
+			assertCaptured(t, buf)
+		})
+	}
+
+	t.Run("Fiber", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := fiber.New()
+		server.Use(FiberMiddleware(ConfigFiber{Capture: capture}))
+		server.Get("/hello", func(ctx *fiber.Ctx) error {
+			ctx.Set("X-Request-Id", "r1")
+			return ctx.SendString("hello")
+		})
+		req := httptest.NewRequest("GET", "/hello?page=2&size=10", nil)
+		req.Header.Set("X-Tenant-Id", "t1")
+		req.Header.Set("Cookie", "session=secret")
+		_, err := server.Test(req)
+		assert.Nil(t, err)
+		assertCaptured(t, buf)
+	})
+}
+
+func assertCaptured(t *testing.T, buf *bytes.Buffer) {
+	t.Logf("Log output %v", buf.String())
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	assert.Equal(t, map[string]interface{}{"X-Tenant-Id": "t1", "Cookie": RedactedValue}, data[RequestHeadersField])
+	assert.Equal(t, map[string]interface{}{"X-Request-Id": "r1"}, data[ResponseHeadersField])
+	assert.Equal(t, map[string]interface{}{"page": "2"}, data[QueryParamsField])
+}
+
+func TestCaptureGrpcMetadata(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	config := ConfigGrpc{Capture: ConfigCapture{RequestHeaders: []string{"x-tenant-id", "authorization"}}}
+	conn, err := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(dialer(config)))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "t1", "authorization", "Bearer token")
+	_, err = pb.NewHelloServiceClient(conn).Hello(ctx, &pb.HelloRequest{Name: "world"})
+	assert.Nil(t, err)
+
+	t.Logf("Log output %v", buf.String())
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	assert.Equal(t, map[string]interface{}{"x-tenant-id": "t1", "authorization": RedactedValue}, data[RequestHeadersField])
+}
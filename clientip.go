@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const RemoteAddrField = "remote_addr"
+
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+// DefaultClientIPHeaders are the headers read by a ClientIPResolver when none is configured.
+var DefaultClientIPHeaders = []string{HeaderForwarded, HeaderXForwardedFor, HeaderXRealIP}
+
+// ConfigClientIP defines a ClientIPResolver.
+type ConfigClientIP struct {
+	// TrustedProxies are the IPs and CIDRs of the proxies trusted to set the client IP headers,
+	// e.g. "10.0.0.0/8".
+	TrustedProxies []string
+
+	// Headers are the headers of the client IP, read in order until one resolves the client IP.
+	// Forwarded is parsed as RFC 7239, X-Forwarded-For as a list of IPs and any other header as
+	// one IP. For gRPC the headers are the incoming metadata. Default is DefaultClientIPHeaders.
+	Headers []string
+}
+
+// ClientIPResolver resolves the IP of the client of a request received through trusted proxies.
+// The headers are only read when the request is received from a trusted proxy, the client IP is
+// the last IP of the headers which is not a trusted proxy. It is safe for concurrent use.
+type ClientIPResolver struct {
+	trusted []*net.IPNet
+	headers []string
+}
+
+// NewClientIPResolver returns the resolver defined by config, or an error when a trusted proxy
+// is not an IP or a CIDR.
+func NewClientIPResolver(config ConfigClientIP) (*ClientIPResolver, error) {
+	if config.Headers == nil {
+		config.Headers = DefaultClientIPHeaders
+	}
+	r := &ClientIPResolver{headers: config.Headers}
+	for _, proxy := range config.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("logger: invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			r.trusted = append(r.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("logger: invalid trusted proxy %q: %w", proxy, err)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+	return r, nil
+}
+
+// Resolve returns the client IP of a request received from remoteAddr, an "ip:port" or an IP,
+// with headers.
+func (r *ClientIPResolver) Resolve(remoteAddr string, headers map[string][]string) string {
+	remote := parseIP(remoteAddr)
+	if remote == nil {
+		return remoteAddr
+	}
+	if !r.isTrusted(remote) {
+		return remote.String()
+	}
+	for _, name := range r.headers {
+		values := headerValues(headers, name)
+		if len(values) == 0 {
+			continue
+		}
+		var ips []net.IP
+		switch {
+		case strings.EqualFold(name, HeaderForwarded):
+			ips = parseForwarded(values)
+		case strings.EqualFold(name, HeaderXForwardedFor):
+			ips = parseIPList(values)
+		default:
+			ips = parseIPList(values[len(values)-1:])
+		}
+		if ip := r.client(ips); ip != nil {
+			return ip.String()
+		}
+	}
+	return remote.String()
+}
+
+// client returns the last IP of ips which is not a trusted proxy, the first IP when every IP is
+// a trusted proxy. It returns nil when an IP is invalid before the client is found, the headers
+// are not trusted then.
+func (r *ClientIPResolver) client(ips []net.IP) net.IP {
+	for i := len(ips) - 1; i >= 0; i-- {
+		if ips[i] == nil {
+			return nil
+		}
+		if !r.isTrusted(ips[i]) || i == 0 {
+			return ips[i]
+		}
+	}
+	return nil
+}
+
+func (r *ClientIPResolver) isTrusted(ip net.IP) bool {
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerValues returns the values of the header name, matched case-insensitively.
+func headerValues(headers map[string][]string, name string) []string {
+	if values, ok := headers[name]; ok {
+		return values
+	}
+	for k, values := range headers {
+		if strings.EqualFold(k, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// parseIPList parses the comma-separated IPs of the values, nil for the invalid IPs.
+func parseIPList(values []string) []net.IP {
+	var ips []net.IP
+	for _, value := range values {
+		for _, s := range strings.Split(value, ",") {
+			ips = append(ips, parseIP(strings.TrimSpace(s)))
+		}
+	}
+	return ips
+}
+
+// parseForwarded parses the for parameters of the RFC 7239 Forwarded values, nil for the
+// obfuscated and unknown nodes.
+func parseForwarded(values []string) []net.IP {
+	var ips []net.IP
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, node, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					ips = append(ips, parseIP(strings.Trim(node, `"`)))
+				}
+			}
+		}
+	}
+	return ips
+}
+
+// parseIP parses an IP with an optional port, "1.2.3.4:80" or "[::1]:80", nil when it is not an IP.
+func parseIP(s string) net.IP {
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+}
@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	pb "github.com/trinhdaiphuc/logger/proto/hello"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	resolver, err := NewClientIPResolver(ConfigClientIP{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/48"}})
+	assert.Nil(t, err)
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string][]string
+		expect  string
+	}{
+		{
+			name:    "Test untrusted remote",
+			remote:  "203.0.113.7:4711",
+			headers: map[string][]string{HeaderXForwardedFor: {"198.51.100.1"}},
+			expect:  "203.0.113.7",
+		},
+		{
+			name:    "Test x-forwarded-for",
+			remote:  "10.0.0.1:4711",
+			headers: map[string][]string{HeaderXForwardedFor: {"198.51.100.9, 198.51.100.1", "10.0.0.2"}},
+			expect:  "198.51.100.1",
+		},
+		{
+			name:    "Test x-forwarded-for of trusted proxies",
+			remote:  "10.0.0.1:4711",
+			headers: map[string][]string{HeaderXForwardedFor: {"10.0.0.3, 10.0.0.2"}},
+			expect:  "10.0.0.3",
+		},
+		{
+			name:    "Test lowercase metadata",
+			remote:  "10.0.0.1:4711",
+			headers: map[string][]string{"x-forwarded-for": {"198.51.100.1"}},
+			expect:  "198.51.100.1",
+		},
+		{
+			name:   "Test forwarded",
+			remote: "[2001:db8::1]:4711",
+			headers: map[string][]string{HeaderForwarded: {
+				`for=198.51.100.9;proto=https, for="[2001:db8:cafe::17]:4711";by=192.0.2.1`,
+				`For="192.0.2.1"`,
+			}},
+			expect: "2001:db8:cafe::17",
+		},
+		{
+			name:   "Test forwarded before x-forwarded-for",
+			remote: "192.0.2.1:4711",
+			headers: map[string][]string{
+				HeaderForwarded:     {"for=198.51.100.2"},
+				HeaderXForwardedFor: {"198.51.100.1"},
+			},
+			expect: "198.51.100.2",
+		},
+		{
+			name:   "Test unknown forwarded node",
+			remote: "10.0.0.1:4711",
+			headers: map[string][]string{
+				HeaderForwarded: {"for=198.51.100.2, for=unknown"},
+				HeaderXRealIP:   {"198.51.100.3"},
+			},
+			expect: "198.51.100.3",
+		},
+		{
+			name:    "Test no header",
+			remote:  "10.0.0.1:4711",
+			headers: map[string][]string{},
+			expect:  "10.0.0.1",
+		},
+		{
+			name:    "Test invalid remote",
+			remote:  "pipe",
+			headers: map[string][]string{HeaderXRealIP: {"198.51.100.3"}},
+			expect:  "pipe",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, resolver.Resolve(tt.remote, tt.headers))
+		})
+	}
+
+	_, err = NewClientIPResolver(ConfigClientIP{TrustedProxies: []string{"10.0.0.0/33"}})
+	assert.NotNil(t, err)
+	_, err = NewClientIPResolver(ConfigClientIP{TrustedProxies: []string{"proxy"}})
+	assert.NotNil(t, err)
+}
+
+func TestClientIPMiddlewares(t *testing.T) {
+	// fiber.App.Test serves the requests from a fake connection of 0.0.0.0.
+	resolver, err := NewClientIPResolver(ConfigClientIP{TrustedProxies: []string{"192.0.2.0/24", "0.0.0.0"}})
+	assert.Nil(t, err)
+	tests := []struct {
+		name   string
+		handle func(req *http.Request)
+		remote string
+	}{
+		{
+			name: "Test gin",
+			handle: func(req *http.Request) {
+				server := gin.New()
+				server.Use(GinMiddleware(ConfigGin{ClientIP: resolver}))
+				server.GET("/hello", func(ctx *gin.Context) {})
+				server.ServeHTTP(httptest.NewRecorder(), req)
+			},
+			remote: "192.0.2.1:1234",
+		},
+		{
+			name: "Test echo",
+			handle: func(req *http.Request) {
+				server := echo.New()
+				server.Use(EchoMiddleware(ConfigEcho{ClientIP: resolver}))
+				server.GET("/hello", func(ctx echo.Context) error { return nil })
+				server.ServeHTTP(httptest.NewRecorder(), req)
+			},
+			remote: "192.0.2.1:1234",
+		},
+		{
+			name: "Test fiber",
+			handle: func(req *http.Request) {
+				server := fiber.New()
+				server.Use(FiberMiddleware(ConfigFiber{ClientIP: resolver}))
+				server.Get("/hello", func(ctx *fiber.Ctx) error { return nil })
+				_, _ = server.Test(req)
+			},
+			remote: "0.0.0.0:0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+			req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set(HeaderXForwardedFor, "198.51.100.1, 192.0.2.2")
+			tt.handle(req)
+
+			data := decodeLog(t, buf)
+			assert.Equal(t, tt.remote, data[RemoteAddrField])
+			assert.Equal(t, "198.51.100.1", data[ClientIPField])
+		})
+	}
+}
+
+func TestClientIPGrpc(t *testing.T) {
+	resolver, err := NewClientIPResolver(ConfigClientIP{TrustedProxies: []string{"127.0.0.1"}})
+	assert.Nil(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := grpc.NewServer(grpc.UnaryInterceptor(GrpcInterceptor(ConfigGrpc{ClientIP: resolver})))
+	pb.RegisterHelloServiceServer(server, &HelloService{})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	assert.Nil(t, err)
+	defer conn.Close()
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-forwarded-for", "198.51.100.1")
+	_, err = pb.NewHelloServiceClient(conn).Hello(ctx, &pb.HelloRequest{Name: "world"})
+	assert.Nil(t, err)
+
+	data := decodeLog(t, buf)
+	assert.Equal(t, "198.51.100.1", data[ClientIPField])
+	assert.Contains(t, data[RemoteAddrField], "127.0.0.1:")
+}
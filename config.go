@@ -5,6 +5,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gofiber/fiber/v2"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 )
 
@@ -15,6 +16,44 @@ type ConfigEcho struct {
 
 	// BeforeFunc defines a function which is executed just before the middleware.
 	BeforeFuncEcho BeforeFuncEcho
+
+	// HandleError calls the error handler of Echo with the error of the handler before logging the
+	// request, so the logged status is the status of the response. The error is not returned to
+	// the previous middlewares.
+	HandleError bool
+
+	// AccessLog defines the Apache/NCSA access log written by the middleware.
+	AccessLog ConfigAccessLog
+
+	// Debug defines the tokens forcing a request log to debug. Default is disabled.
+	Debug ConfigDebug
+
+	// Levels defines the levels of the request logs set at runtime. Default is DefaultLevelRegistry.
+	Levels *LevelRegistry
+
+	// Metrics records the request count, duration and requests in flight. Default is disabled.
+	Metrics Metrics
+
+	// ClientIP resolves the client IP behind trusted proxies. Default is the client IP of the
+	// framework.
+	ClientIP *ClientIPResolver
+
+	// Recovery defines the recovery from the panics of the handlers.
+	Recovery ConfigRecovery
+
+	// Capture defines which headers and query parameters are logged.
+	Capture ConfigCapture
+
+	// Schema defines the field names of the request log. Default is DefaultSchema.
+	Schema Schema
+
+	// ErrorFormatter defines the log fields of the errors of the handlers. Default is
+	// DefaultErrorFormatter.
+	ErrorFormatter ErrorFormatter
+
+	// Formatter defines the formatter of the request log. Default is ConsoleFormatter when the
+	// output is a terminal, JSONFormatter otherwise.
+	Formatter Formatter
 }
 
 type (
@@ -38,6 +77,47 @@ type ConfigGin struct {
 
 	// BeforeFunc defines a function which is executed just before the middleware.
 	BeforeFuncGin BeforeFuncGin
+
+	// ErrorTypes is the mask of the gin error types logged. Default is gin.ErrorTypeAny.
+	ErrorTypes gin.ErrorType
+
+	// ErrorLevels maps gin error types to the level of the request log, the request is logged at the
+	// worst level of its errors. Errors of other types do not change the level. Default is
+	// DefaultGinErrorLevels.
+	ErrorLevels map[gin.ErrorType]logrus.Level
+
+	// AccessLog defines the Apache/NCSA access log written by the middleware.
+	AccessLog ConfigAccessLog
+
+	// Debug defines the tokens forcing a request log to debug. Default is disabled.
+	Debug ConfigDebug
+
+	// Levels defines the levels of the request logs set at runtime. Default is DefaultLevelRegistry.
+	Levels *LevelRegistry
+
+	// Metrics records the request count, duration and requests in flight. Default is disabled.
+	Metrics Metrics
+
+	// ClientIP resolves the client IP behind trusted proxies. Default is the client IP of the
+	// framework.
+	ClientIP *ClientIPResolver
+
+	// Recovery defines the recovery from the panics of the handlers.
+	Recovery ConfigRecovery
+
+	// Capture defines which headers and query parameters are logged.
+	Capture ConfigCapture
+
+	// Schema defines the field names of the request log. Default is DefaultSchema.
+	Schema Schema
+
+	// ErrorFormatter defines the log fields of the errors of the handlers. Default is
+	// DefaultErrorFormatter.
+	ErrorFormatter ErrorFormatter
+
+	// Formatter defines the formatter of the request log. Default is ConsoleFormatter when the
+	// output is a terminal, JSONFormatter otherwise.
+	Formatter Formatter
 }
 
 type (
@@ -61,6 +141,44 @@ type ConfigFiber struct {
 
 	// BeforeFunc defines a function which is executed just before the middleware.
 	BeforeFuncFiber BeforeFuncFiber
+
+	// HandleError calls the error handler of Fiber with the error of the handler before logging the
+	// request, so the logged status is the status of the response. The error is not returned to
+	// the previous middlewares.
+	HandleError bool
+
+	// AccessLog defines the Apache/NCSA access log written by the middleware.
+	AccessLog ConfigAccessLog
+
+	// Debug defines the tokens forcing a request log to debug. Default is disabled.
+	Debug ConfigDebug
+
+	// Levels defines the levels of the request logs set at runtime. Default is DefaultLevelRegistry.
+	Levels *LevelRegistry
+
+	// Metrics records the request count, duration and requests in flight. Default is disabled.
+	Metrics Metrics
+
+	// ClientIP resolves the client IP behind trusted proxies. Default is the client IP of the
+	// framework.
+	ClientIP *ClientIPResolver
+
+	// Recovery defines the recovery from the panics of the handlers.
+	Recovery ConfigRecovery
+
+	// Capture defines which headers and query parameters are logged.
+	Capture ConfigCapture
+
+	// Schema defines the field names of the request log. Default is DefaultSchema.
+	Schema Schema
+
+	// ErrorFormatter defines the log fields of the errors of the handlers. Default is
+	// DefaultErrorFormatter.
+	ErrorFormatter ErrorFormatter
+
+	// Formatter defines the formatter of the request log. Default is ConsoleFormatter when the
+	// output is a terminal, JSONFormatter otherwise.
+	Formatter Formatter
 }
 
 type (
@@ -84,6 +202,36 @@ type ConfigGrpc struct {
 
 	// BeforeFunc defines a function which is executed just before the middleware.
 	BeforeFuncGrpc BeforeFuncGrpc
+
+	// Debug defines the tokens forcing a request log to debug. Default is disabled.
+	Debug ConfigDebug
+
+	// Levels defines the levels of the request logs set at runtime. Default is DefaultLevelRegistry.
+	Levels *LevelRegistry
+
+	// Metrics records the request count, duration and requests in flight. Default is disabled.
+	Metrics Metrics
+
+	// ClientIP resolves the client IP behind trusted proxies from the x-forwarded-for metadata.
+	// Default is the address of the peer.
+	ClientIP *ClientIPResolver
+
+	// Recovery defines the recovery from the panics of the handlers.
+	Recovery ConfigRecovery
+
+	// Capture defines which headers (metadata) are logged.
+	Capture ConfigCapture
+
+	// Schema defines the field names of the request log. Default is DefaultSchema.
+	Schema Schema
+
+	// ErrorFormatter defines the log fields of the errors of the handlers. Default is
+	// DefaultErrorFormatter.
+	ErrorFormatter ErrorFormatter
+
+	// Formatter defines the formatter of the request log. Default is ConsoleFormatter when the
+	// output is a terminal, JSONFormatter otherwise.
+	Formatter Formatter
 }
 
 type (
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+const (
+	RequestSizeField      = "request_size"
+	ResponseSizeField     = "response_size"
+	ProtocolField         = "protocol"
+	SchemeField           = "scheme"
+	HostField             = "host"
+	TLSVersionField       = "tls_version"
+	TLSCipherField        = "tls_cipher"
+	TLSServerNameField    = "tls_server_name"
+	TLSClientSubjectField = "tls_client_subject"
+	AuthTypeField         = "auth_type"
+)
+
+// tlsVersions are the names of the TLS versions, tls.VersionName is go1.21.
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// tlsVersionName returns the name of a TLS version, e.g. "TLS 1.3".
+func tlsVersionName(version uint16) string {
+	if name, ok := tlsVersions[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+// tlsFields returns the version, the cipher suite, the server name and the subject of the client
+// certificate of a TLS connection, nil when the connection is not TLS.
+func tlsFields(schema Schema, state *tls.ConnectionState) map[string]interface{} {
+	if state == nil || !state.HandshakeComplete {
+		return nil
+	}
+	fields := map[string]interface{}{
+		schema.TLSVersion: tlsVersionName(state.Version),
+		schema.TLSCipher:  tls.CipherSuiteName(state.CipherSuite),
+	}
+	if state.ServerName != "" {
+		fields[schema.TLSServerName] = state.ServerName
+	}
+	if len(state.PeerCertificates) > 0 {
+		fields[schema.TLSClientSubject] = state.PeerCertificates[0].Subject.String()
+	}
+	return fields
+}
+
+// httpFields returns the content length, the protocol, the scheme, the host, the TLS and the
+// trace fields of an HTTP request.
+func httpFields(schema Schema, req *http.Request, scheme string) map[string]interface{} {
+	fields := map[string]interface{}{
+		schema.Protocol: req.Proto,
+		schema.Scheme:   scheme,
+		schema.Host:     req.Host,
+	}
+	if req.ContentLength >= 0 {
+		fields[schema.RequestSize] = req.ContentLength
+	}
+	for k, v := range tlsFields(schema, req.TLS) {
+		fields[k] = v
+	}
+	for k, v := range traceFields(schema, req.Header.Get) {
+		fields[k] = v
+	}
+	return fields
+}
+
+// httpScheme returns the scheme of an HTTP request received by the server.
+func httpScheme(req *http.Request) string {
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	pb "github.com/trinhdaiphuc/logger/proto/hello"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTLSFields(t *testing.T) {
+	schema := DefaultSchema
+	assert.Nil(t, tlsFields(schema, nil))
+	assert.Nil(t, tlsFields(schema, &tls.ConnectionState{}))
+	assert.Equal(t, map[string]interface{}{
+		TLSVersionField:       "TLS 1.3",
+		TLSCipherField:        "TLS_AES_128_GCM_SHA256",
+		TLSServerNameField:    "api.example.com",
+		TLSClientSubjectField: "CN=client,O=Example",
+	}, tlsFields(schema, &tls.ConnectionState{
+		HandshakeComplete: true,
+		Version:           tls.VersionTLS13,
+		CipherSuite:       tls.TLS_AES_128_GCM_SHA256,
+		ServerName:        "api.example.com",
+		PeerCertificates: []*x509.Certificate{
+			{Subject: pkix.Name{CommonName: "client", Organization: []string{"Example"}}},
+		},
+	}))
+	assert.Equal(t, "0x0300", tlsVersionName(0x0300))
+}
+
+func TestConnectionFieldsTLS(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	router := gin.New()
+	router.Use(GinMiddleware(ConfigGin{}))
+	router.POST("/upload", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "uploaded")
+	})
+	server := httptest.NewTLSServer(router)
+	defer server.Close()
+	client := server.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.ServerName = "example.com"
+
+	res, err := client.Post(server.URL+"/upload", "text/plain", strings.NewReader("file content"))
+	assert.Nil(t, err)
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	data := decodeLog(t, buf)
+	assert.Equal(t, float64(12), data[RequestSizeField])
+	assert.Equal(t, float64(8), data[ResponseSizeField])
+	assert.Equal(t, "HTTP/1.1", data[ProtocolField])
+	assert.Equal(t, "https", data[SchemeField])
+	assert.Equal(t, strings.TrimPrefix(server.URL, "https://"), data[HostField])
+	assert.Equal(t, "TLS 1.3", data[TLSVersionField])
+	assert.NotEmpty(t, data[TLSCipherField])
+	assert.Equal(t, "example.com", data[TLSServerNameField])
+	_, ok := data[TLSClientSubjectField]
+	assert.False(t, ok, `unexpected "%v" field: %v`, TLSClientSubjectField, data)
+}
+
+func TestConnectionFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(req *http.Request)
+	}{
+		{
+			name: "Test echo",
+			handle: func(req *http.Request) {
+				server := echo.New()
+				server.Use(EchoMiddleware(ConfigEcho{}))
+				server.POST("/upload", func(ctx echo.Context) error {
+					return ctx.String(http.StatusOK, "uploaded")
+				})
+				server.ServeHTTP(httptest.NewRecorder(), req)
+			},
+		},
+		{
+			name: "Test fiber",
+			handle: func(req *http.Request) {
+				server := fiber.New()
+				server.Use(FiberMiddleware(ConfigFiber{}))
+				server.Post("/upload", func(ctx *fiber.Ctx) error {
+					return ctx.SendString("uploaded")
+				})
+				_, _ = server.Test(req)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+			tt.handle(httptest.NewRequest(http.MethodPost, "http://api.example.com/upload", strings.NewReader("file content")))
+
+			data := decodeLog(t, buf)
+			assert.Equal(t, float64(12), data[RequestSizeField])
+			assert.Equal(t, float64(8), data[ResponseSizeField])
+			assert.Equal(t, "HTTP/1.1", data[ProtocolField])
+			assert.Equal(t, "http", data[SchemeField])
+			assert.Equal(t, "api.example.com", data[HostField])
+			_, ok := data[TLSVersionField]
+			assert.False(t, ok, `unexpected "%v" field: %v`, TLSVersionField, data)
+		})
+	}
+}
+
+func TestConnectionFieldsGrpc(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "", grpc.WithInsecure(), grpc.WithContextDialer(dialer(ConfigGrpc{})))
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	request := &pb.HelloRequest{Name: "world"}
+	response, err := pb.NewHelloServiceClient(conn).Hello(ctx, request)
+	assert.Nil(t, err)
+
+	data := decodeLog(t, buf)
+	assert.Equal(t, float64(proto.Size(request)), data[RequestSizeField])
+	assert.Equal(t, float64(proto.Size(response)), data[ResponseSizeField])
+	_, ok := data[AuthTypeField]
+	assert.False(t, ok, `unexpected "%v" field: %v`, AuthTypeField, data)
+}
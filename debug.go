@@ -0,0 +1,257 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DebugHeader is the default header, or lower case gRPC metadata key, forcing a request log to debug.
+	DebugHeader = "X-Debug-Log"
+	// DebugField is logged with true in the request logs forced to debug. Samplers must keep these logs.
+	DebugField = "debug"
+	// DefaultMaxBodySize is the default number of bytes of the request and response bodies logged.
+	DefaultMaxBodySize = 64 << 10
+)
+
+// ConfigDebug defines the tokens of the debug header. A request with a valid token is logged at debug
+// level, is never sampled, logs its request and response bodies, and its token is propagated to the
+// outbound calls made with DebugTransport and DebugClientInterceptor. Debug forcing is disabled
+// when Tokens, Secret and Verify are empty.
+type ConfigDebug struct {
+	// Header is the header of the debug token, the gRPC metadata key is its lower case. Default is DebugHeader.
+	Header string
+
+	// Tokens is the allowlist of debug tokens.
+	Tokens []string
+
+	// Secret verifies the tokens signed by SignDebugToken.
+	Secret string
+
+	// Verify is a custom verification of the debug tokens.
+	Verify func(token string) bool
+
+	// MaxBodySize is the maximum number of bytes of the bodies logged. Default is DefaultMaxBodySize.
+	MaxBodySize int
+}
+
+// SignDebugToken returns a debug token valid until expires for the ConfigDebug with secret.
+func SignDebugToken(secret string, expires time.Time) string {
+	expiry := strconv.FormatInt(expires.Unix(), 10)
+	return expiry + "." + debugSignature(secret, expiry)
+}
+
+func debugSignature(secret, expiry string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(expiry))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// debugger is the compiled ConfigDebug of a middleware.
+type debugger struct {
+	config ConfigDebug
+	header string
+	key    string
+}
+
+// newDebugger returns nil when debug forcing is disabled.
+func newDebugger(config ConfigDebug) *debugger {
+	if len(config.Tokens) == 0 && config.Secret == "" && config.Verify == nil {
+		return nil
+	}
+	if config.Header == "" {
+		config.Header = DebugHeader
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = DefaultMaxBodySize
+	}
+	return &debugger{
+		config: config,
+		header: http.CanonicalHeaderKey(config.Header),
+		key:    strings.ToLower(config.Header),
+	}
+}
+
+// verify reports whether token forces the request log to debug.
+func (d *debugger) verify(token string) bool {
+	if d == nil || token == "" {
+		return false
+	}
+	for _, allowed := range d.config.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+			return true
+		}
+	}
+	if d.config.Secret != "" {
+		if expiry, signature, ok := strings.Cut(token, "."); ok {
+			expires, err := strconv.ParseInt(expiry, 10, 64)
+			if err == nil && time.Now().Unix() <= expires &&
+				hmac.Equal([]byte(signature), []byte(debugSignature(d.config.Secret, expiry))) {
+				return true
+			}
+		}
+	}
+	return d.config.Verify != nil && d.config.Verify(token)
+}
+
+// headerName returns the debug header, empty when debug forcing is disabled.
+func (d *debugger) headerName() string {
+	if d == nil {
+		return ""
+	}
+	return d.header
+}
+
+// metadataToken returns the debug token of the incoming gRPC metadata of ctx.
+func (d *debugger) metadataToken(ctx context.Context) string {
+	if d == nil {
+		return ""
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(d.key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// truncate returns at most MaxBodySize bytes of body.
+func (d *debugger) truncate(body []byte) string {
+	if len(body) > d.config.MaxBodySize {
+		body = body[:d.config.MaxBodySize]
+	}
+	return string(body)
+}
+
+// readBody returns at most MaxBodySize bytes of the body of req and restores it for the handler.
+func (d *debugger) readBody(req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody {
+		return ""
+	}
+	body, _ := io.ReadAll(io.LimitReader(req.Body, int64(d.config.MaxBodySize)))
+	req.Body = &readCloser{Reader: io.MultiReader(bytes.NewReader(body), req.Body), Closer: req.Body}
+	return string(body)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyBuffer keeps the first max bytes of a response body.
+type bodyBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *bodyBuffer) keep(p []byte) {
+	if remaining := b.max - b.Len(); remaining > 0 {
+		if len(p) < remaining {
+			remaining = len(p)
+		}
+		b.Write(p[:remaining])
+	}
+}
+
+// bodyWriter is a http.ResponseWriter keeping the first MaxBodySize bytes written.
+type bodyWriter struct {
+	http.ResponseWriter
+	body *bodyBuffer
+}
+
+func (d *debugger) bodyWriter(w http.ResponseWriter) *bodyWriter {
+	return &bodyWriter{ResponseWriter: w, body: &bodyBuffer{max: d.config.MaxBodySize}}
+}
+
+func (w *bodyWriter) Write(b []byte) (int, error) {
+	w.body.keep(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *bodyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("hijack not supported")
+}
+
+// debugRequest is the debug token of a request log forced to debug.
+type debugRequest struct {
+	header string
+	token  string
+}
+
+// forceDebug logs the request at debug level and keeps the token to propagate it.
+func (l *Log) forceDebug(d *debugger, token string) {
+	l.SetLevel(logrus.DebugLevel)
+	l.Lock()
+	l.debug = &debugRequest{header: d.header, token: token}
+	l.Unlock()
+	l.WithField(DebugField, true)
+}
+
+// IsDebugForced reports whether the request log has been forced to debug by a debug token.
+func (l *Log) IsDebugForced() bool {
+	l.Lock()
+	defer l.Unlock()
+	return l.debug != nil
+}
+
+// debugFromContext returns the debug token of the request log of ctx.
+func debugFromContext(ctx context.Context) *debugRequest {
+	if l, ok := ctx.Value(Key).(*Log); ok {
+		l.Lock()
+		defer l.Unlock()
+		return l.debug
+	}
+	return nil
+}
+
+// DebugTransport propagates the debug token of the request log of the context of the outbound
+// requests. Base is http.DefaultTransport when nil.
+type DebugTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if debug := debugFromContext(req.Context()); debug != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set(debug.header, debug.token)
+	}
+	return base.RoundTrip(req)
+}
+
+// DebugClientInterceptor propagates the debug token of the request log of the context of the
+// outbound gRPC calls as metadata.
+func DebugClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if debug := debugFromContext(ctx); debug != nil {
+			ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(debug.header), debug.token)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
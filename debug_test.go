@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	pb "github.com/trinhdaiphuc/logger/proto/hello"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebuggerVerify(t *testing.T) {
+	var (
+		secret   = "secret"
+		debugger = newDebugger(ConfigDebug{
+			Tokens: []string{"support"},
+			Secret: secret,
+			Verify: func(token string) bool { return token == "custom" },
+		})
+	)
+	tests := []struct {
+		name   string
+		token  string
+		expect bool
+	}{
+		{name: "Test empty token", token: "", expect: false},
+		{name: "Test allowlisted token", token: "support", expect: true},
+		{name: "Test custom token", token: "custom", expect: true},
+		{name: "Test signed token", token: SignDebugToken(secret, time.Now().Add(time.Minute)), expect: true},
+		{name: "Test expired token", token: SignDebugToken(secret, time.Now().Add(-time.Minute)), expect: false},
+		{name: "Test token of another secret", token: SignDebugToken("other", time.Now().Add(time.Minute)), expect: false},
+		{name: "Test unknown token", token: "unknown", expect: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, debugger.verify(tt.token))
+		})
+	}
+
+	assert.Nil(t, newDebugger(ConfigDebug{}))
+	assert.False(t, newDebugger(ConfigDebug{}).verify("support"))
+}
+
+func TestDebugMiddlewares(t *testing.T) {
+	var (
+		debug = ConfigDebug{Tokens: []string{"support"}, MaxBodySize: 8}
+		body  = `{"name":"world"}`
+	)
+	assertDebugLog := func(t *testing.T, buf *bytes.Buffer) {
+		data := decodeLog(t, buf)
+		assert.Equal(t, true, data[DebugField])
+		assert.Equal(t, "debug step", data["STEP_1"])
+		assert.Equal(t, body[:8], data[RequestField])
+		assert.Equal(t, "Hello wo", data[ResponseField])
+	}
+	newRequest := func(token string) *http.Request {
+		req := httptest.NewRequest("POST", "/hello", strings.NewReader(body))
+		req.Header.Set(DebugHeader, token)
+		return req
+	}
+
+	t.Run("Gin", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := gin.New()
+		server.Use(GinMiddleware(ConfigGin{Debug: debug}))
+		server.POST("/hello", func(ctx *gin.Context) {
+			GetLogger(ctx).AddDebugLog("debug step")
+			data, _ := io.ReadAll(ctx.Request.Body)
+			assert.Equal(t, body, string(data))
+			ctx.String(http.StatusOK, "Hello world")
+		})
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, newRequest("support"))
+		assert.Equal(t, "Hello world", w.Body.String())
+		assertDebugLog(t, buf)
+
+		buf.Reset()
+		server.ServeHTTP(httptest.NewRecorder(), newRequest("unknown"))
+		data := decodeLog(t, buf)
+		_, ok := data[DebugField]
+		assert.False(t, ok, "unexpected debug field: %v", data)
+		_, ok = data["STEP_1"]
+		assert.False(t, ok, "unexpected debug step: %v", data)
+	})
+
+	t.Run("Echo", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := echo.New()
+		server.Use(EchoMiddleware(ConfigEcho{Debug: debug}))
+		server.POST("/hello", func(ctx echo.Context) error {
+			GetLogger(ctx.Request().Context()).AddDebugLog("debug step")
+			data, _ := io.ReadAll(ctx.Request().Body)
+			assert.Equal(t, body, string(data))
+			return ctx.String(http.StatusOK, "Hello world")
+		})
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, newRequest("support"))
+		assert.Equal(t, "Hello world", w.Body.String())
+		assertDebugLog(t, buf)
+	})
+
+	t.Run("Fiber", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := fiber.New()
+		server.Use(FiberMiddleware(ConfigFiber{Debug: debug}))
+		server.Post("/hello", func(ctx *fiber.Ctx) error {
+			GetLogger(ctx.Context()).AddDebugLog("debug step")
+			return ctx.SendString("Hello world")
+		})
+		_, err := server.Test(newRequest("support"))
+		assert.Nil(t, err)
+		assertDebugLog(t, buf)
+	})
+
+	t.Run("Grpc", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		conn, err := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(dialer(ConfigGrpc{Debug: debug})))
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		ctx := metadata.AppendToOutgoingContext(context.Background(), strings.ToLower(DebugHeader), "support")
+		_, err = pb.NewHelloServiceClient(conn).Hello(ctx, &pb.HelloRequest{Name: "debug"})
+		assert.Nil(t, err)
+		data := decodeLog(t, buf)
+		assert.Equal(t, true, data[DebugField])
+		assert.Equal(t, "debug step", data["STEP_2"])
+	})
+}
+
+func TestDebugStandardLevel(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	defer logrus.SetLevel(logrus.InfoLevel)
+	debug := ConfigDebug{Tokens: []string{"support"}}
+
+	t.Run("Gin", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := gin.New()
+		server.Use(GinMiddleware(ConfigGin{Debug: debug}))
+		server.GET("/hello", func(ctx *gin.Context) {
+			logger := GetLogger(ctx)
+			logger.AddDebugLog("debug step")
+			logger.Debug("handler line")
+		})
+		req := httptest.NewRequest("GET", "/hello", nil)
+		req.Header.Set(DebugHeader, "support")
+		server.ServeHTTP(httptest.NewRecorder(), req)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if assert.Len(t, lines, 2) {
+			assert.Contains(t, lines[0], `"msg":"handler line"`)
+			data := decodeLog(t, bytes.NewBufferString(lines[1]))
+			assert.Equal(t, true, data[DebugField])
+			assert.Equal(t, "debug step", data["STEP_1"])
+		}
+
+		buf.Reset()
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/hello", nil))
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("Grpc", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		conn, err := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(dialer(ConfigGrpc{Debug: debug})))
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		ctx := metadata.AppendToOutgoingContext(context.Background(), strings.ToLower(DebugHeader), "support")
+		_, err = pb.NewHelloServiceClient(conn).Hello(ctx, &pb.HelloRequest{Name: "debug"})
+		assert.Nil(t, err)
+		data := decodeLog(t, buf)
+		assert.Equal(t, true, data[DebugField])
+		assert.Equal(t, "debug step", data["STEP_2"])
+	})
+}
+
+func TestDebugPropagation(t *testing.T) {
+	logger := New()
+	logger.forceDebug(newDebugger(ConfigDebug{Tokens: []string{"support"}}), "support")
+	ctx := context.WithValue(context.Background(), Key, logger)
+
+	t.Run("Test transport", func(t *testing.T) {
+		var header string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Get(DebugHeader)
+		}))
+		defer server.Close()
+		client := &http.Client{Transport: &DebugTransport{}}
+
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+		resp, err := client.Do(req)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "support", header)
+		assert.Empty(t, req.Header.Get(DebugHeader), "the outbound request must not be modified")
+
+		req, _ = http.NewRequest("GET", server.URL, nil)
+		resp, err = client.Do(req)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		assert.Empty(t, header)
+	})
+
+	t.Run("Test client interceptor", func(t *testing.T) {
+		var token []string
+		invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			token = md.Get(DebugHeader)
+			return nil
+		}
+		assert.Nil(t, DebugClientInterceptor()(ctx, "/hello.HelloService/Hello", nil, nil, nil, invoker))
+		assert.Equal(t, []string{"support"}, token)
+	})
+}
@@ -2,8 +2,12 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"reflect"
 	"time"
 )
 
@@ -15,10 +19,26 @@ func EchoMiddleware(config ConfigEcho) echo.MiddlewareFunc {
 	if config.SkipperEcho == nil {
 		config.SkipperEcho = DefaultSkipperEcho
 	}
+	if config.Formatter == nil {
+		config.Formatter = AutoFormatter(logrus.StandardLogger().Out)
+	}
+	if config.Levels == nil {
+		config.Levels = DefaultLevelRegistry
+	}
+	if config.Metrics == nil {
+		config.Metrics = noMetrics{}
+	}
+	if config.ErrorFormatter == nil {
+		config.ErrorFormatter = DefaultErrorFormatter
+	}
+	schema := config.Schema.withDefaults()
+	capture := newCapturer(config.Capture, schema)
+	debug := newDebugger(config.Debug)
+	accessLog := newAccessLogger(config.AccessLog)
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(ctx echo.Context) error {
-			logger := New(WithFormatter(&JSONFormatter{}))
+			logger := New(WithFormatter(config.Formatter))
 			if config.SkipperEcho(ctx) {
 				return next(ctx)
 			}
@@ -31,44 +51,146 @@ func EchoMiddleware(config ConfigEcho) echo.MiddlewareFunc {
 				method    = ctx.Request().Method
 				userAgent = ctx.Request().UserAgent()
 				uri       = ctx.Request().RequestURI
-				errs      string
+				route     = echoRoute(ctx)
 				start     = time.Now()
-				isError   bool
+				level     = logrus.InfoLevel
 			)
+			defer config.Metrics.TrackHTTP(method)()
+			if config.ClientIP != nil {
+				clientIP = config.ClientIP.Resolve(ctx.Request().RemoteAddr, ctx.Request().Header)
+			}
 
 			logger.WithFields(map[string]interface{}{
-				ClientIPField:      clientIP,
-				RequestMethodField: method,
-				UserAgentField:     userAgent,
-				URIField:           uri,
+				schema.ClientIP:      clientIP,
+				schema.RemoteAddr:    ctx.Request().RemoteAddr,
+				schema.RequestMethod: method,
+				schema.UserAgent:     userAgent,
+				schema.URI:           uri,
+				schema.Route:         routeLabel(route),
+				schema.Path:          ctx.Request().URL.Path,
 			})
+			if query := ctx.QueryString(); query != "" {
+				logger.WithField(schema.Query, query)
+			}
+			logger.WithFields(httpFields(schema, ctx.Request(), ctx.Scheme()))
+			if capture != nil {
+				logger.WithFields(capture.requestFields(ctx.Request().Header, ctx.QueryParams()))
+			}
 
+			logger.SetLevel(config.Levels.Level(method, route))
+			var body *bodyBuffer
+			if token := ctx.Request().Header.Get(debug.headerName()); debug.verify(token) {
+				logger.forceDebug(debug, token)
+				logger.WithField(schema.Request, debug.readBody(ctx.Request()))
+				writer := debug.bodyWriter(ctx.Response().Writer)
+				ctx.Response().Writer, body = writer, writer.body
+			}
 			ctx.SetRequest(ctx.Request().WithContext(context.WithValue(ctx.Request().Context(), Key, logger)))
 			ctx.Set(Key, logger)
 
-			err := next(ctx)
+			p, err := config.Recovery.call(func() error {
+				return next(ctx)
+			})
 
+			if p != nil {
+				logger.recordPanic(schema, p)
+				err = echo.NewHTTPError(http.StatusInternalServerError).SetInternal(p)
+			}
+			if err != nil && config.HandleError && !(p != nil && config.Recovery.RePanic) {
+				ctx.Error(err)
+			}
 			var (
-				statusCode = ctx.Response().Status
+				statusCode = echoStatus(ctx, err)
 			)
-			logger.WithField(StatusField, statusCode)
-			if err != nil {
-				isError = true
-				errs = err.Error()
+			logger.WithFields(map[string]interface{}{
+				schema.Status:       statusCode,
+				schema.ResponseSize: ctx.Response().Size,
+			})
+			if he, ok := echoHTTPError(err); ok {
+				logger.WithFields(map[string]interface{}{
+					schema.ErrorCode:    he.Code,
+					schema.ErrorMessage: he.Message,
+				})
 			}
-
-			if len(errs) > 0 {
-				logger.WithField(ErrorsField, errs)
+			if capture != nil {
+				logger.WithFields(capture.responseFields(ctx.Response().Header()))
+			}
+			if body != nil {
+				logger.WithField(schema.Response, body.String())
+			}
+			if err != nil {
+				level = logrus.ErrorLevel
+				logger.withError(config.ErrorFormatter, schema, err)
 			}
 			end := time.Now()
-			logger.WithField(EndField, end)
+			logger.WithField(schema.End, end)
+			if schema.Latency != "" {
+				logger.WithField(schema.Latency, end.Sub(start))
+			}
 			msg := fmt.Sprintf("latency: %v", end.Sub(start))
-			if isError {
-				logger.Error(msg)
-			} else {
-				logger.Info(msg)
+			logger.finish(msg, level, p, config.Recovery)
+			config.Metrics.ObserveHTTP(method, routeLabel(route), statusCode, end.Sub(start))
+			if accessLog != nil {
+				user, _, _ := ctx.Request().BasicAuth()
+				accessLog.write(accessLogEntry{
+					host:      clientIP,
+					user:      user,
+					time:      start,
+					method:    method,
+					uri:       uri,
+					proto:     ctx.Request().Proto,
+					status:    statusCode,
+					size:      ctx.Response().Size,
+					referer:   ctx.Request().Referer(),
+					userAgent: userAgent,
+					requestID: ctx.Request().Header.Get(accessLog.config.RequestIDHeader),
+					latency:   end.Sub(start),
+				})
+			}
+			if p != nil && config.Recovery.RePanic {
+				panic(p.value)
+			}
+			if config.HandleError {
+				return nil
 			}
 			return err
 		}
 	}
 }
+
+// echoRoute returns the route matched by the router of Echo, empty when the request matched no
+// route: Echo then calls its NotFoundHandler with the path of the request as route.
+func echoRoute(ctx echo.Context) string {
+	if handler := ctx.Handler(); handler != nil &&
+		reflect.ValueOf(handler).Pointer() == reflect.ValueOf(echo.NotFoundHandler).Pointer() {
+		return ""
+	}
+	return ctx.Path()
+}
+
+// echoStatus returns the status of the response, or the status the error handler of Echo will
+// answer with when the response is not committed yet.
+func echoStatus(ctx echo.Context, err error) int {
+	if err == nil || ctx.Response().Committed {
+		return ctx.Response().Status
+	}
+	// The default error handler of Echo only answers with the code of an unwrapped HTTPError.
+	if _, ok := err.(*echo.HTTPError); ok {
+		he, _ := echoHTTPError(err)
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// echoHTTPError returns the *echo.HTTPError found in the chain of err, or its internal
+// *echo.HTTPError as the default error handler of Echo does.
+func echoHTTPError(err error) (*echo.HTTPError, bool) {
+	var he *echo.HTTPError
+	if !errors.As(err, &he) {
+		return nil, false
+	}
+	if internal, ok := he.Internal.(*echo.HTTPError); ok {
+		return internal, true
+	}
+	return he, true
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
@@ -146,3 +147,115 @@ func performRequest(r http.Handler, method, path string) *httptest.ResponseRecor
 	r.ServeHTTP(w, req)
 	return w
 }
+
+func TestEchoMiddlewareHTTPError(t *testing.T) {
+	tests := []struct {
+		name         string
+		handleError  bool
+		err          error
+		status       int
+		errorCode    interface{}
+		errorMessage interface{}
+	}{
+		{
+			name:         "Test http error",
+			err:          echo.NewHTTPError(http.StatusNotFound, "user not found"),
+			status:       http.StatusNotFound,
+			errorCode:    float64(http.StatusNotFound),
+			errorMessage: "user not found",
+		},
+		{
+			name:         "Test handle http error",
+			handleError:  true,
+			err:          echo.NewHTTPError(http.StatusBadRequest).SetInternal(echo.NewHTTPError(http.StatusConflict, "conflict")),
+			status:       http.StatusConflict,
+			errorCode:    float64(http.StatusConflict),
+			errorMessage: "conflict",
+		},
+		{
+			name:         "Test wrapped http error",
+			err:          WrapError(echo.NewHTTPError(http.StatusNotFound, "user not found")).With("user_id", "u1"),
+			status:       http.StatusInternalServerError,
+			errorCode:    float64(http.StatusNotFound),
+			errorMessage: "user not found",
+		},
+		{
+			name:         "Test handle fmt wrapped http error",
+			handleError:  true,
+			err:          fmt.Errorf("get user: %w", echo.NewHTTPError(http.StatusNotFound, "user not found")),
+			status:       http.StatusInternalServerError,
+			errorCode:    float64(http.StatusNotFound),
+			errorMessage: "user not found",
+		},
+		{
+			name:   "Test plain error",
+			err:    errors.New("test err"),
+			status: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+			server := echo.New()
+			var returned error
+			server.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(ctx echo.Context) error {
+					returned = next(ctx)
+					return returned
+				}
+			})
+			server.Use(EchoMiddleware(ConfigEcho{HandleError: tt.handleError}))
+			server.GET("/users/:id", func(ctx echo.Context) error {
+				return tt.err
+			})
+			w := performRequest(server, "GET", "/users/u1")
+
+			t.Logf("Log output %v", buf.String())
+			var data map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+				t.Error("unexpected error", err)
+			}
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, float64(tt.status), data[StatusField])
+			assert.Equal(t, tt.errorCode, data[ErrorCodeField])
+			assert.Equal(t, tt.errorMessage, data[ErrorMessageField])
+			assert.Equal(t, "error", data[FieldKeyLevel])
+			if tt.handleError {
+				assert.Nil(t, returned)
+			} else {
+				assert.Equal(t, tt.err, returned)
+			}
+		})
+	}
+}
+
+func TestEchoMiddlewareWrappedHTTPErrorHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	server := echo.New()
+	server.HTTPErrorHandler = func(err error, ctx echo.Context) {
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			_ = ctx.JSON(he.Code, he.Message)
+			return
+		}
+		server.DefaultHTTPErrorHandler(err, ctx)
+	}
+	server.Use(EchoMiddleware(ConfigEcho{HandleError: true}))
+	server.GET("/users/:id", func(ctx echo.Context) error {
+		return WrapError(echo.NewHTTPError(http.StatusNotFound, "user not found")).With("user_id", "u1")
+	})
+	w := performRequest(server, "GET", "/users/u1")
+
+	t.Logf("Log output %v", buf.String())
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, float64(http.StatusNotFound), data[StatusField])
+	assert.Equal(t, float64(http.StatusNotFound), data[ErrorCodeField])
+	assert.Equal(t, "user not found", data[ErrorMessageField])
+	assert.Equal(t, "u1", data["user_id"])
+}
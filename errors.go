@@ -0,0 +1,262 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+const (
+	ErrorTypesField   = "error_types"
+	ErrorChainField   = "error_chain"
+	ErrorCodeField    = "error_code"
+	ErrorMessageField = "error_message"
+	ErrorDetailsField = "error_details"
+	ErrorReasonField  = "error_reason"
+	ErrorDomainField  = "error_domain"
+	FieldErrorsField  = "field_errors"
+	RetryDelayField   = "retry_delay"
+
+	// maxErrorDepth limits the number of errors walked in an error tree.
+	maxErrorDepth = 32
+)
+
+// FieldsCarrier is implemented by errors which contribute their own fields to the request log.
+type FieldsCarrier interface {
+	error
+	LogFields() map[string]interface{}
+}
+
+// ErrorFormatter returns the log fields of an error returned by a handler.
+type ErrorFormatter func(schema Schema, err error) map[string]interface{}
+
+// ErrorDetail describes one error of an error chain.
+type ErrorDetail struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// stackError is an error annotated with the stack trace of WithStack.
+type stackError struct {
+	error
+	stack []uintptr
+}
+
+// WithStack annotates err with the stack trace of the caller. It returns nil when err is nil.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &stackError{error: err, stack: pcs[:n]}
+}
+
+func (e *stackError) Unwrap() error {
+	return e.error
+}
+
+// StackTrace returns the program counters of the stack trace.
+func (e *stackError) StackTrace() []uintptr {
+	return e.stack
+}
+
+// Error is an error carrying log fields. The middlewares merge the fields of the errors returned
+// by the handlers into the request log, so the logger does not have to be passed down.
+type Error struct {
+	err    error
+	fields map[string]interface{}
+}
+
+// Errorf formats an error like fmt.Errorf, %w wraps its operand.
+func Errorf(format string, args ...interface{}) *Error {
+	return &Error{err: fmt.Errorf(format, args...)}
+}
+
+// WrapError returns an Error wrapping err. It returns nil when err is nil.
+func WrapError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	return &Error{err: err}
+}
+
+// With returns a copy of the error with a new key = value field
+func (e *Error) With(key string, value interface{}) *Error {
+	return e.WithFields(map[string]interface{}{key: value})
+}
+
+// WithFields returns a copy of the error with multiple key/value fields: key1 = value1, key2 = value2
+func (e *Error) WithFields(fields map[string]interface{}) *Error {
+	merged := make(map[string]interface{}, len(e.fields)+len(fields))
+	for k, v := range e.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Error{err: e.err, fields: merged}
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// LogFields returns the fields of the error.
+func (e *Error) LogFields() map[string]interface{} {
+	return e.fields
+}
+
+// GRPCStatus returns the status of the wrapped error, so a wrapped gRPC status error keeps its code.
+func (e *Error) GRPCStatus() *status.Status {
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(e.err, &se) {
+		return se.GRPCStatus()
+	}
+	return status.New(codes.Unknown, e.Error())
+}
+
+// ErrorFields returns the fields of every FieldsCarrier of the tree of err. The fields of the
+// outer errors override the fields of the errors they wrap.
+func ErrorFields(err error) map[string]interface{} {
+	var carriers []FieldsCarrier
+	walkErrors(err, func(e error) {
+		if c, ok := e.(FieldsCarrier); ok {
+			carriers = append(carriers, c)
+		}
+	})
+	if len(carriers) == 0 {
+		return nil
+	}
+	fields := make(map[string]interface{})
+	for i := len(carriers) - 1; i >= 0; i-- {
+		for k, v := range carriers[i].LogFields() {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// DefaultErrorFormatter logs the message of err, the message and type of every error of its tree
+// walked with Unwrap() error and Unwrap() []error, and the deepest stack trace found in the tree.
+func DefaultErrorFormatter(schema Schema, err error) map[string]interface{} {
+	if err == nil {
+		return nil
+	}
+	var (
+		fields = make(map[string]interface{})
+		chain  []ErrorDetail
+		types  []string
+		stack  string
+	)
+	walkErrors(err, func(e error) {
+		chain = append(chain, ErrorDetail{Message: e.Error(), Type: fmt.Sprintf("%T", e)})
+		types = append(types, fmt.Sprintf("%T", e))
+		if s := errorStack(e); s != "" {
+			stack = s
+		}
+	})
+
+	fields[schema.Errors] = err.Error()
+	fields[schema.ErrorTypes] = types
+	if len(chain) > 1 {
+		fields[schema.ErrorChain] = chain
+	}
+	if stack != "" {
+		fields[schema.Stack] = stack
+	}
+	return fields
+}
+
+// walkErrors calls fn for err and every error it wraps, depth first.
+func walkErrors(err error, fn func(error)) {
+	var (
+		depth int
+		walk  func(error)
+	)
+	walk = func(e error) {
+		if e == nil || depth >= maxErrorDepth {
+			return
+		}
+		depth++
+		fn(e)
+		switch u := e.(type) {
+		case interface{ Unwrap() []error }:
+			for _, inner := range u.Unwrap() {
+				walk(inner)
+			}
+		default:
+			walk(errors.Unwrap(e))
+		}
+	}
+	walk(err)
+}
+
+// errorStack returns the stack trace of an error created by WithStack or by github.com/pkg/errors,
+// which has a StackTrace method returning a slice of program counters.
+func errorStack(err error) string {
+	var pcs []uintptr
+	if s, ok := err.(interface{ StackTrace() []uintptr }); ok {
+		pcs = s.StackTrace()
+	} else {
+		method := reflect.ValueOf(err).MethodByName("StackTrace")
+		if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+			return ""
+		}
+		out := method.Call(nil)[0]
+		if out.Kind() != reflect.Slice || out.Type().Elem().Kind() != reflect.Uintptr {
+			return ""
+		}
+		pcs = make([]uintptr, out.Len())
+		for i := range pcs {
+			pcs[i] = uintptr(out.Index(i).Uint())
+		}
+	}
+	if len(pcs) == 0 {
+		return ""
+	}
+
+	var (
+		b      strings.Builder
+		frames = runtime.CallersFrames(pcs)
+	)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// joinedErrors is the go1.20 errors.Join error, used to format several errors at once.
+type joinedErrors []error
+
+func (e joinedErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e joinedErrors) Unwrap() []error {
+	return e
+}
+
+// withError adds the fields of the FieldsCarrier errors of err and the fields of formatter to log.
+func (l *Log) withError(formatter ErrorFormatter, schema Schema, err error) {
+	if fields := ErrorFields(err); fields != nil {
+		l.WithFields(fields)
+	}
+	l.WithFields(formatter(schema, err))
+}
@@ -0,0 +1,245 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	pb "github.com/trinhdaiphuc/logger/proto/hello"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+// pkgFrame and pkgStackError mimic the errors of github.com/pkg/errors.
+type pkgFrame uintptr
+
+type pkgStackError struct {
+	msg   string
+	stack []pkgFrame
+}
+
+func (e *pkgStackError) Error() string {
+	return e.msg
+}
+
+func (e *pkgStackError) StackTrace() []pkgFrame {
+	return e.stack
+}
+
+func newPkgStackError(msg string) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	stack := make([]pkgFrame, n)
+	for i := range stack {
+		stack[i] = pkgFrame(pcs[i])
+	}
+	return &pkgStackError{msg: msg, stack: stack}
+}
+
+type userError struct {
+	userID string
+	err    error
+}
+
+func (e *userError) Error() string {
+	return "user " + e.userID + ": " + e.err.Error()
+}
+
+func (e *userError) Unwrap() error {
+	return e.err
+}
+
+func (e *userError) LogFields() map[string]interface{} {
+	return map[string]interface{}{"user_id": e.userID, "source": "user"}
+}
+
+func TestDefaultErrorFormatter(t *testing.T) {
+	var (
+		schema = DefaultSchema
+		base   = errors.New("not found")
+	)
+	t.Run("Test nil error", func(t *testing.T) {
+		assert.Nil(t, DefaultErrorFormatter(schema, nil))
+	})
+
+	t.Run("Test wrapped chain", func(t *testing.T) {
+		err := fmt.Errorf("get user: %w", base)
+		fields := DefaultErrorFormatter(schema, err)
+		assert.Equal(t, "get user: not found", fields[ErrorsField])
+		assert.Equal(t, []string{"*fmt.wrapError", "*errors.errorString"}, fields[ErrorTypesField])
+		assert.Equal(t, []ErrorDetail{
+			{Message: "get user: not found", Type: "*fmt.wrapError"},
+			{Message: "not found", Type: "*errors.errorString"},
+		}, fields[ErrorChainField])
+		_, ok := fields[StackField]
+		assert.False(t, ok, "unexpected stack field: %v", fields)
+	})
+
+	t.Run("Test joined errors", func(t *testing.T) {
+		err := joinedErrors{base, errors.New("timeout")}
+		fields := DefaultErrorFormatter(schema, err)
+		assert.Equal(t, "not found\ntimeout", fields[ErrorsField])
+		assert.Len(t, fields[ErrorChainField], 3)
+	})
+
+	t.Run("Test with stack", func(t *testing.T) {
+		err := fmt.Errorf("get user: %w", WithStack(base))
+		fields := DefaultErrorFormatter(schema, err)
+		assert.Contains(t, fields[StackField], "TestDefaultErrorFormatter")
+		assert.Contains(t, fields[StackField], "errors_test.go")
+	})
+
+	t.Run("Test pkg errors stack", func(t *testing.T) {
+		fields := DefaultErrorFormatter(schema, newPkgStackError("boom"))
+		assert.Contains(t, fields[StackField], "newPkgStackError")
+	})
+
+	t.Run("Test custom schema", func(t *testing.T) {
+		fields := DefaultErrorFormatter(Schema{Errors: "error.message"}.withDefaults(), base)
+		assert.Equal(t, "not found", fields["error.message"])
+	})
+}
+
+func TestErrorFields(t *testing.T) {
+	base := errors.New("not found")
+	tests := []struct {
+		name   string
+		err    error
+		expect map[string]interface{}
+	}{
+		{
+			name:   "Test no fields",
+			err:    base,
+			expect: nil,
+		},
+		{
+			name:   "Test fields carrier",
+			err:    fmt.Errorf("handler: %w", &userError{userID: "u1", err: base}),
+			expect: map[string]interface{}{"user_id": "u1", "source": "user"},
+		},
+		{
+			name: "Test outer fields override inner fields",
+			err: Errorf("handler: %w", &userError{userID: "u1", err: base}).
+				With("source", "handler").
+				With("retry", 2),
+			expect: map[string]interface{}{"user_id": "u1", "source": "handler", "retry": 2},
+		},
+		{
+			name:   "Test joined errors",
+			err:    joinedErrors{Errorf("first").With("a", 1), WrapError(base).WithFields(map[string]interface{}{"b": 2})},
+			expect: map[string]interface{}{"a": 1, "b": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, ErrorFields(tt.err))
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	base := errors.New("not found")
+	err := Errorf("get user %v: %w", "u1", base)
+	withField := err.With("user_id", "u1")
+
+	assert.Equal(t, "get user u1: not found", withField.Error())
+	assert.True(t, errors.Is(withField, base))
+	assert.Nil(t, err.LogFields(), "With must not modify the original error")
+	assert.Equal(t, map[string]interface{}{"user_id": "u1"}, withField.LogFields())
+	assert.Nil(t, WrapError(nil))
+
+	assert.Equal(t, codes.Unknown, status.Code(withField))
+	assert.Equal(t, codes.NotFound, status.Code(WrapError(status.Error(codes.NotFound, "not found")).With("k", "v")))
+}
+
+func TestWithStackNil(t *testing.T) {
+	assert.Nil(t, WithStack(nil))
+}
+
+func TestErrorFormatterMiddleware(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	server := echo.New()
+	server.Use(EchoMiddleware(DefaultConfigEcho))
+	server.GET("/users/:id", func(ctx echo.Context) error {
+		return WithStack(&userError{userID: ctx.Param("id"), err: errors.New("not found")})
+	})
+	performRequest(server, "GET", "/users/u1")
+
+	t.Logf("Log output %v", buf.String())
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	assert.Equal(t, "user u1: not found", data[ErrorsField])
+	assert.Equal(t, "u1", data["user_id"])
+	assert.Equal(t, []interface{}{"*logger.stackError", "*logger.userError", "*errors.errorString"}, data[ErrorTypesField])
+	assert.Contains(t, data[StackField], "errors_test.go")
+	assert.Equal(t, "error", data[FieldKeyLevel])
+}
+
+func TestErrorFieldsMiddlewares(t *testing.T) {
+	t.Run("Gin", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := gin.New()
+		server.Use(GinMiddleware(DefaultConfigGin))
+		server.GET("/users/:id", func(ctx *gin.Context) {
+			ctx.Error(Errorf("user not found").With("user_id", ctx.Param("id")))
+			ctx.Error(Errorf("cache miss").With("cache", "users"))
+			ctx.Status(http.StatusNotFound)
+		})
+		performRequest(server, "GET", "/users/u1")
+		data := decodeLog(t, buf)
+		assert.Equal(t, "u1", data["user_id"])
+		assert.Equal(t, "users", data["cache"])
+	})
+
+	t.Run("Fiber", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := fiber.New()
+		server.Use(FiberMiddleware(DefaultConfigFiber))
+		server.Get("/users/:id", func(ctx *fiber.Ctx) error {
+			return Errorf("user not found").With("user_id", ctx.Params("id"))
+		})
+		_, err := server.Test(httptest.NewRequest("GET", "/users/u1", nil))
+		assert.Nil(t, err)
+		data := decodeLog(t, buf)
+		assert.Equal(t, "u1", data["user_id"])
+	})
+
+	t.Run("Grpc", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		conn, err := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(dialer(DefaultConfigGrpc)))
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		_, err = pb.NewHelloServiceClient(conn).Hello(context.Background(), &pb.HelloRequest{Name: "unknown"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		data := decodeLog(t, buf)
+		assert.Equal(t, "unknown", data["name"])
+		assert.Equal(t, codes.NotFound.String(), data[CodeField])
+	})
+}
+
+func decodeLog(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Logf("Log output %v", buf.String())
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	return data
+}
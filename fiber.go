@@ -1,8 +1,10 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
 	"time"
 )
 
@@ -14,9 +16,25 @@ func FiberMiddleware(config ConfigFiber) fiber.Handler {
 	if config.SkipperFiber == nil {
 		config.SkipperFiber = DefaultSkipperFiber
 	}
+	if config.Formatter == nil {
+		config.Formatter = AutoFormatter(logrus.StandardLogger().Out)
+	}
+	if config.Levels == nil {
+		config.Levels = DefaultLevelRegistry
+	}
+	if config.Metrics == nil {
+		config.Metrics = noMetrics{}
+	}
+	if config.ErrorFormatter == nil {
+		config.ErrorFormatter = DefaultErrorFormatter
+	}
+	schema := config.Schema.withDefaults()
+	capture := newCapturer(config.Capture, schema)
+	debug := newDebugger(config.Debug)
+	accessLog := newAccessLogger(config.AccessLog)
 
 	return func(ctx *fiber.Ctx) error {
-		logger := New(WithFormatter(&JSONFormatter{}))
+		logger := New(WithFormatter(config.Formatter))
 		if config.SkipperFiber(ctx) {
 			return ctx.Next()
 		}
@@ -29,42 +47,157 @@ func FiberMiddleware(config ConfigFiber) fiber.Handler {
 			method    = ctx.Method()
 			userAgent = string(ctx.Request().Header.UserAgent())
 			uri       = string(ctx.Request().Header.RequestURI())
-			errs      string
 			start     = time.Now()
-			isError   bool
+			level     = logrus.InfoLevel
+			remote    = ctx.Context().RemoteAddr().String()
 		)
+		defer config.Metrics.TrackHTTP(method)()
+		if config.ClientIP != nil {
+			clientIP = config.ClientIP.Resolve(remote, visitValues(ctx.Request().Header.VisitAll))
+		}
 
 		logger.WithFields(map[string]interface{}{
-			ClientIPField:      clientIP,
-			RequestMethodField: method,
-			UserAgentField:     userAgent,
-			URIField:           uri,
+			schema.ClientIP:      clientIP,
+			schema.RemoteAddr:    remote,
+			schema.RequestMethod: method,
+			schema.UserAgent:     userAgent,
+			schema.URI:           uri,
+			schema.Path:          ctx.Path(),
 		})
+		if query := ctx.Request().URI().QueryString(); len(query) > 0 {
+			logger.WithField(schema.Query, string(query))
+		}
+		logger.WithFields(map[string]interface{}{
+			schema.Protocol: string(ctx.Request().Header.Protocol()),
+			schema.Scheme:   ctx.Protocol(),
+			schema.Host:     ctx.Hostname(),
+		})
+		if size := ctx.Request().Header.ContentLength(); size >= 0 {
+			logger.WithField(schema.RequestSize, size)
+		}
+		logger.WithFields(tlsFields(schema, ctx.Context().TLSConnectionState()))
+		logger.WithFields(traceFields(schema, func(name string) string { return ctx.Get(name) }))
+		if capture != nil {
+			logger.WithFields(capture.requestFields(
+				visitValues(ctx.Request().Header.VisitAll),
+				visitValues(ctx.Request().URI().QueryArgs().VisitAll),
+			))
+		}
 
+		// Fiber matches the route of the handler when it is called, so the level is resolved from
+		// the current route when it is read by the handler.
+		logger.SetLevel(config.Levels.Level(method, ""))
+		logger.setLevelFunc(func() logrus.Level {
+			return config.Levels.Level(method, ctx.Route().Path)
+		})
+		token := ctx.Get(debug.headerName())
+		forced := debug.verify(token)
+		if forced {
+			logger.forceDebug(debug, token)
+			logger.WithField(schema.Request, debug.truncate(ctx.Body()))
+		}
 		ctx.Context().SetUserValue(Key, logger)
 
-		err := ctx.Next()
+		p, err := config.Recovery.call(ctx.Next)
+
+		// The route of the handler is only known once it is matched by the router.
+		route := fiberRoute(ctx, err)
+		logger.WithField(schema.Route, routeLabel(route))
+		if !forced {
+			logger.SetLevel(config.Levels.Level(method, route))
+		}
 
+		if p != nil {
+			logger.recordPanic(schema, p)
+			err = fiber.ErrInternalServerError
+		}
+		if err != nil && config.HandleError && !(p != nil && config.Recovery.RePanic) {
+			if handlerErr := ctx.App().ErrorHandler(ctx, err); handlerErr != nil {
+				_ = ctx.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
 		var (
-			statusCode = ctx.Response().StatusCode()
+			statusCode = fiberStatus(ctx, err, config.HandleError)
 		)
-		logger.WithField(StatusField, statusCode)
-		if err != nil {
-			isError = true
-			errs = err.Error()
+		logger.WithFields(map[string]interface{}{
+			schema.Status:       statusCode,
+			schema.ResponseSize: len(ctx.Response().Body()),
+		})
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			logger.WithFields(map[string]interface{}{
+				schema.ErrorCode:    fe.Code,
+				schema.ErrorMessage: fe.Message,
+			})
 		}
-
-		if len(errs) > 0 {
-			logger.WithField(ErrorsField, errs)
+		if capture != nil {
+			logger.WithFields(capture.responseFields(visitValues(ctx.Response().Header.VisitAll)))
+		}
+		if forced {
+			logger.WithField(schema.Response, debug.truncate(ctx.Response().Body()))
+		}
+		if err != nil {
+			level = logrus.ErrorLevel
+			logger.withError(config.ErrorFormatter, schema, err)
 		}
 		end := time.Now()
-		logger.WithField(EndField, end)
+		logger.WithField(schema.End, end)
+		if schema.Latency != "" {
+			logger.WithField(schema.Latency, end.Sub(start))
+		}
 		msg := fmt.Sprintf("latency: %v", end.Sub(start))
-		if isError {
-			logger.Error(msg)
-		} else {
-			logger.Info(msg)
+		logger.finish(msg, level, p, config.Recovery)
+		config.Metrics.ObserveHTTP(method, routeLabel(route), statusCode, end.Sub(start))
+		if accessLog != nil {
+			accessLog.write(accessLogEntry{
+				host:      clientIP,
+				user:      basicAuthUser(ctx.Get(fiber.HeaderAuthorization)),
+				time:      start,
+				method:    method,
+				uri:       uri,
+				proto:     string(ctx.Request().Header.Protocol()),
+				status:    statusCode,
+				size:      int64(len(ctx.Response().Body())),
+				referer:   ctx.Get(fiber.HeaderReferer),
+				userAgent: userAgent,
+				requestID: ctx.Get(accessLog.config.RequestIDHeader),
+				latency:   end.Sub(start),
+			})
+		}
+		if p != nil && config.Recovery.RePanic {
+			panic(p.value)
+		}
+		if config.HandleError {
+			return nil
 		}
 		return err
 	}
 }
+
+// fiberRoute returns the route matched by the router of Fiber, empty when the request matched no
+// route. The route of the context is then the last Use route called, and the router returns a
+// 404 "Cannot METHOD PATH" error, or ErrMethodNotAllowed when the path matches another method.
+func fiberRoute(ctx *fiber.Ctx, err error) string {
+	if err == fiber.ErrMethodNotAllowed {
+		return ""
+	}
+	var fe *fiber.Error
+	if errors.As(err, &fe) && fe.Code == fiber.StatusNotFound &&
+		fe.Message == "Cannot "+ctx.Method()+" "+string(ctx.Request().URI().PathOriginal()) {
+		return ""
+	}
+	return ctx.Route().Path
+}
+
+// fiberStatus returns the status of the response, or the status the default error handler of
+// Fiber will answer with when the error is not handled yet.
+func fiberStatus(ctx *fiber.Ctx, err error, handled bool) int {
+	if err == nil || handled {
+		return ctx.Response().StatusCode()
+	}
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return fe.Code
+	}
+	return fiber.StatusInternalServerError
+}
@@ -193,3 +193,61 @@ func performFiberRequest(app *fiber.App, method, path string) (*http.Response, e
 	req := httptest.NewRequest(method, path, nil)
 	return app.Test(req)
 }
+
+func TestFiberMiddlewareFiberError(t *testing.T) {
+	tests := []struct {
+		name         string
+		handleError  bool
+		errorHandler fiber.ErrorHandler
+		err          error
+		status       int
+		errorCode    interface{}
+		errorMessage interface{}
+	}{
+		{
+			name:         "Test fiber error",
+			err:          fiber.NewError(http.StatusNotFound, "user not found"),
+			status:       http.StatusNotFound,
+			errorCode:    float64(http.StatusNotFound),
+			errorMessage: "user not found",
+		},
+		{
+			name:        "Test handle error with custom error handler",
+			handleError: true,
+			errorHandler: func(ctx *fiber.Ctx, err error) error {
+				return ctx.Status(http.StatusTeapot).SendString(err.Error())
+			},
+			err:    errors.New("test err"),
+			status: http.StatusTeapot,
+		},
+		{
+			name:   "Test plain error",
+			err:    errors.New("test err"),
+			status: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+			server := fiber.New(fiber.Config{ErrorHandler: tt.errorHandler})
+			server.Use(FiberMiddleware(ConfigFiber{HandleError: tt.handleError}))
+			server.Get("/users/:id", func(ctx *fiber.Ctx) error {
+				return tt.err
+			})
+			w, err := performFiberRequest(server, "GET", "/users/u1")
+			assert.Nil(t, err)
+
+			t.Logf("Log output %v", buf.String())
+			var data map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+				t.Error("unexpected error", err)
+			}
+			assert.Equal(t, tt.status, w.StatusCode)
+			assert.Equal(t, float64(tt.status), data[StatusField])
+			assert.Equal(t, tt.errorCode, data[ErrorCodeField])
+			assert.Equal(t, tt.errorMessage, data[ErrorMessageField])
+			assert.Equal(t, "error", data[FieldKeyLevel])
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// rotatedTimeFormat is the time suffix of the rotated files.
+	rotatedTimeFormat = "2006-01-02T15-04-05.000"
+	// compressSuffix is the suffix of the compressed rotated files.
+	compressSuffix = ".gz"
+)
+
+// ConfigFile defines a FileWriter.
+type ConfigFile struct {
+	// Filename is the file of the logs. The rotated files are in the same directory, named with
+	// the time of the rotation: app-2006-01-02T15-04-05.000.log.
+	Filename string
+
+	// MaxSize rotates the file before it grows over MaxSize bytes. Default is no size rotation.
+	MaxSize int64
+
+	// Interval rotates the file at every multiple of Interval, e.g. 24 * time.Hour rotates the
+	// file every day at midnight UTC. Default is no time rotation.
+	Interval time.Duration
+
+	// Compress gzips the rotated files.
+	Compress bool
+
+	// MaxFiles is the number of rotated files kept. Default keeps every file.
+	MaxFiles int
+
+	// MaxAge is the duration the rotated files are kept. Default keeps every file.
+	MaxAge time.Duration
+
+	// LocalTime names the rotated files with the local time instead of UTC.
+	LocalTime bool
+
+	// ReopenOnSIGHUP reopens the file on SIGHUP, after it has been moved by an external logrotate.
+	ReopenOnSIGHUP bool
+
+	// FileMode is the mode of the created files. Default is 0644.
+	FileMode os.FileMode
+}
+
+// FileWriter writes the logs to a file rotated by size and time. It is safe for concurrent use.
+type FileWriter struct {
+	config ConfigFile
+	now    func() time.Time
+	rename func(oldpath, newpath string) error
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	rotateAt time.Time
+	closed   bool
+
+	compress sync.WaitGroup
+	cleanup  sync.Mutex
+	signals  chan os.Signal
+	done     chan struct{}
+}
+
+// NewFileWriter opens the file of config, appending to it when it exists.
+func NewFileWriter(config ConfigFile) (*FileWriter, error) {
+	if config.Filename == "" {
+		return nil, errors.New("logger: empty filename")
+	}
+	if config.FileMode == 0 {
+		config.FileMode = 0644
+	}
+	w := &FileWriter{config: config, now: time.Now, rename: os.Rename, done: make(chan struct{})}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	if config.ReopenOnSIGHUP {
+		w.signals = make(chan os.Signal, 1)
+		signal.Notify(w.signals, syscall.SIGHUP)
+		go w.handleSignals()
+	}
+	return w, nil
+}
+
+// Write writes p to the file, rotating it before when p would exceed MaxSize or the rotation
+// interval is over. When the rotation fails, p is still written to the file and the error of the
+// rotation is returned.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	if w.needsRotation(int64(len(p))) {
+		rotateErr = w.rotate()
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+func (w *FileWriter) needsRotation(n int64) bool {
+	if w.config.MaxSize > 0 && w.size > 0 && w.size+n > w.config.MaxSize {
+		return true
+	}
+	return !w.rotateAt.IsZero() && !w.now().Before(w.rotateAt)
+}
+
+// Rotate rotates the file.
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes and reopens the file, after it has been moved by an external logrotate.
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Close waits for the compression of the rotated files and closes the file.
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	if w.signals != nil {
+		signal.Stop(w.signals)
+		close(w.done)
+	}
+	err := w.closeFile()
+	w.mu.Unlock()
+	w.compress.Wait()
+	return err
+}
+
+// closeFile closes the file. The file is nil until it is opened again, even when closing fails,
+// so the next write opens it again.
+func (w *FileWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *FileWriter) handleSignals() {
+	for {
+		select {
+		case <-w.signals:
+			_ = w.Reopen()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// open opens the file and schedules the next time rotation.
+func (w *FileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.config.Filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.config.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, w.config.FileMode)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file, w.size = file, info.Size()
+	if w.config.Interval > 0 {
+		w.rotateAt = w.now().Truncate(w.config.Interval).Add(w.config.Interval)
+	}
+	return nil
+}
+
+// rotate renames the file with the time of the rotation, opens a new file, then compresses and
+// removes the rotated files in the background. When the file cannot be renamed, it is opened
+// again so the writes go on.
+func (w *FileWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	now := w.now()
+	rotated := w.rotatedName(now)
+	if err := w.rename(w.config.Filename, rotated); err != nil && !os.IsNotExist(err) {
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.compress.Add(1)
+	go func() {
+		defer w.compress.Done()
+		w.cleanup.Lock()
+		defer w.cleanup.Unlock()
+		if w.config.Compress {
+			_ = compressFile(rotated)
+		}
+		w.removeOldFiles(now)
+	}()
+	return nil
+}
+
+// rotatedName returns the name of a file rotated at t. The time is moved forward by a
+// millisecond while a file with the same name exists, so rotations are never overwritten.
+func (w *FileWriter) rotatedName(t time.Time) string {
+	if !w.config.LocalTime {
+		t = t.UTC()
+	}
+	prefix, ext := w.nameParts()
+	for {
+		name := prefix + t.Format(rotatedTimeFormat) + ext
+		if !fileExists(name) && !fileExists(name+compressSuffix) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// nameParts returns the prefix and extension of the rotated files.
+func (w *FileWriter) nameParts() (prefix, ext string) {
+	ext = filepath.Ext(w.config.Filename)
+	return strings.TrimSuffix(w.config.Filename, ext) + "-", ext
+}
+
+// rotatedFile is a rotated file with the time of its rotation.
+type rotatedFile struct {
+	path string
+	time time.Time
+}
+
+// removeOldFiles removes the rotated files over MaxFiles and older than MaxAge.
+func (w *FileWriter) removeOldFiles(now time.Time) {
+	if w.config.MaxFiles <= 0 && w.config.MaxAge <= 0 {
+		return
+	}
+	prefix, ext := w.nameParts()
+	paths, err := filepath.Glob(prefix + "*")
+	if err != nil {
+		return
+	}
+	var files []rotatedFile
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimSuffix(path, compressSuffix), ext)
+		t, err := time.Parse(rotatedTimeFormat, strings.TrimPrefix(name, prefix))
+		if err != nil {
+			continue
+		}
+		files = append(files, rotatedFile{path: path, time: t})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].time.After(files[j].time)
+	})
+
+	if w.config.LocalTime {
+		// The rotated names have no time zone, compare them in the same zone.
+		now = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), time.UTC)
+	}
+	for i, file := range files {
+		if (w.config.MaxFiles > 0 && i >= w.config.MaxFiles) ||
+			(w.config.MaxAge > 0 && now.Sub(file.time) > w.config.MaxAge) {
+			_ = os.Remove(file.path)
+		}
+	}
+}
+
+// compressFile gzips path to path.gz and removes path.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(path + compressSuffix)
+		}
+	}()
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,196 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// rotatedFiles returns the sorted names of the rotated files of dir.
+func rotatedFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() != "app.log" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	return string(data)
+}
+
+func TestFileWriterSize(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		filename = filepath.Join(dir, "app.log")
+		now      = time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)
+	)
+	w, err := NewFileWriter(ConfigFile{Filename: filename, MaxSize: 10})
+	assert.Nil(t, err)
+	w.now = func() time.Time { return now }
+
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n"} {
+		_, err = w.Write([]byte(line))
+		assert.Nil(t, err)
+		now = now.Add(time.Second)
+	}
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, "line 3\n", readFile(t, filename))
+	assert.Equal(t, []string{"app-2022-08-01T10-00-01.000.log", "app-2022-08-01T10-00-02.000.log"}, rotatedFiles(t, dir))
+	assert.Equal(t, "line 1\n", readFile(t, filepath.Join(dir, "app-2022-08-01T10-00-01.000.log")))
+}
+
+func TestFileWriterInterval(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		filename = filepath.Join(dir, "app.log")
+		now      = time.Date(2022, 8, 1, 23, 59, 0, 0, time.UTC)
+	)
+	w, err := NewFileWriter(ConfigFile{Filename: filename})
+	assert.Nil(t, err)
+	w.config.Interval = 24 * time.Hour
+	w.now = func() time.Time { return now }
+	assert.Nil(t, w.Reopen())
+
+	fmt.Fprintln(w, "day 1")
+	now = now.Add(2 * time.Minute)
+	fmt.Fprintln(w, "day 2")
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, "day 2\n", readFile(t, filename))
+	assert.Equal(t, []string{"app-2022-08-02T00-01-00.000.log"}, rotatedFiles(t, dir))
+}
+
+func TestFileWriterCompressAndRetention(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		filename = filepath.Join(dir, "app.log")
+		now      = time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)
+	)
+	// A rotated file older than MaxAge.
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "app-2022-07-01T10-00-00.000.log.gz"), nil, 0644))
+
+	w, err := NewFileWriter(ConfigFile{Filename: filename, Compress: true, MaxFiles: 2, MaxAge: 7 * 24 * time.Hour})
+	assert.Nil(t, err)
+	w.now = func() time.Time { return now }
+	for i := 1; i <= 4; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+		now = now.Add(time.Second)
+		assert.Nil(t, w.Rotate())
+	}
+	assert.Nil(t, w.Close())
+
+	files := rotatedFiles(t, dir)
+	assert.Equal(t, []string{"app-2022-08-01T10-00-03.000.log.gz", "app-2022-08-01T10-00-04.000.log.gz"}, files)
+
+	f, err := os.Open(filepath.Join(dir, files[1]))
+	assert.Nil(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	assert.Nil(t, err)
+	data, err := io.ReadAll(gz)
+	assert.Nil(t, err)
+	assert.Equal(t, "line 4\n", string(data))
+}
+
+func TestFileWriterReopen(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		filename = filepath.Join(dir, "app.log")
+	)
+	w, err := NewFileWriter(ConfigFile{Filename: filename, ReopenOnSIGHUP: true})
+	assert.Nil(t, err)
+	defer w.Close()
+
+	fmt.Fprintln(w, "before")
+	assert.Nil(t, os.Rename(filename, filepath.Join(dir, "app.log.1")))
+	process, err := os.FindProcess(os.Getpid())
+	assert.Nil(t, err)
+	assert.Nil(t, process.Signal(syscall.SIGHUP))
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filename)
+		return err == nil
+	}, time.Second, time.Millisecond)
+	fmt.Fprintln(w, "after")
+
+	assert.Equal(t, "before\n", readFile(t, filepath.Join(dir, "app.log.1")))
+	assert.Equal(t, "after\n", readFile(t, filename))
+}
+
+func TestFileWriterRenameError(t *testing.T) {
+	var (
+		dir       = t.TempDir()
+		filename  = filepath.Join(dir, "app.log")
+		now       = time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)
+		renameErr = &os.LinkError{Op: "rename", Err: syscall.ENOSPC}
+	)
+	w, err := NewFileWriter(ConfigFile{Filename: filename, MaxSize: 10})
+	assert.Nil(t, err)
+	w.now = func() time.Time { return now }
+	w.rename = func(oldpath, newpath string) error { return renameErr }
+
+	_, err = w.Write([]byte("line 1\n"))
+	assert.Nil(t, err)
+	n, err := w.Write([]byte("line 2\n"))
+	assert.Equal(t, renameErr, err)
+	assert.Equal(t, 7, n)
+	assert.Equal(t, "line 1\nline 2\n", readFile(t, filename))
+
+	// The rotation succeeds once the rename does.
+	w.rename = os.Rename
+	_, err = w.Write([]byte("line 3\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	assert.Equal(t, "line 3\n", readFile(t, filename))
+	assert.Equal(t, []string{"app-2022-08-01T10-00-00.000.log"}, rotatedFiles(t, dir))
+	assert.Equal(t, "line 1\nline 2\n", readFile(t, filepath.Join(dir, "app-2022-08-01T10-00-00.000.log")))
+}
+
+func TestFileWriterConcurrency(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		filename = filepath.Join(dir, "app.log")
+		wg       sync.WaitGroup
+	)
+	w, err := NewFileWriter(ConfigFile{Filename: filename, MaxSize: 1024})
+	assert.Nil(t, err)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fmt.Fprintf(w, "goroutine %d line %d\n", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Nil(t, w.Close())
+
+	var lines int
+	for _, name := range append(rotatedFiles(t, dir), "app.log") {
+		content := readFile(t, filepath.Join(dir, name))
+		assert.LessOrEqual(t, len(content), 1024)
+		lines += strings.Count(content, "\n")
+	}
+	assert.Equal(t, 800, lines)
+
+	_, err = w.Write([]byte("closed\n"))
+	assert.Equal(t, os.ErrClosed, err)
+}
@@ -0,0 +1,290 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/mattn/go-isatty"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const stepPrefix = "STEP_"
+
+const (
+	colorRed    = 31
+	colorGreen  = 32
+	colorYellow = 33
+	colorBlue   = 34
+	colorCyan   = 36
+	colorGray   = 37
+)
+
+// LogfmtFormatter formats logs into strict logfmt: time, level and msg first, then the fields
+// sorted by key. Values which are not strings, numbers or booleans are encoded as json.
+type LogfmtFormatter struct {
+	// TimestampFormat sets the format used for marshaling timestamps. Default is time.RFC3339.
+	TimestampFormat string
+
+	// DisableTimestamp allows disabling automatic timestamps in output
+	DisableTimestamp bool
+}
+
+// Format renders a single log entry
+func (f *LogfmtFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := entryBuffer(entry)
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = time.RFC3339
+	}
+	if !f.DisableTimestamp {
+		appendKeyValue(b, logrus.FieldKeyTime, entry.Time.Format(timestampFormat))
+	}
+	appendKeyValue(b, logrus.FieldKeyLevel, entry.Level.String())
+	appendKeyValue(b, logrus.FieldKeyMsg, entry.Message)
+	if entry.HasCaller() {
+		appendKeyValue(b, logrus.FieldKeyFunc, entry.Caller.Function)
+		appendKeyValue(b, logrus.FieldKeyFile, fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line))
+	}
+	for _, key := range sortedKeys(entry.Data) {
+		appendKeyValue(b, key, entry.Data[key])
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// ConsoleFormatter is a human-friendly formatter for local development. It prints the summary
+// of the request on one line, with the status colored by class, followed by the numbered steps
+// and the other fields on indented lines.
+type ConsoleFormatter struct {
+	// Schema defines the field names used by the middlewares. Default is DefaultSchema.
+	Schema Schema
+
+	// DisableColors disables the ANSI colors.
+	DisableColors bool
+
+	// TimestampFormat sets the format used for the timestamp. Default is "15:04:05.000".
+	TimestampFormat string
+}
+
+// Format renders a single log entry
+func (f *ConsoleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	var (
+		b      = entryBuffer(entry)
+		schema = f.Schema.withDefaults()
+		used   = make(map[string]bool)
+	)
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = "15:04:05.000"
+	}
+	b.WriteString(entry.Time.Format(timestampFormat))
+	b.WriteByte(' ')
+	b.WriteString(f.colorize(levelColor(entry.Level), fmt.Sprintf("%-5.5s", strings.ToUpper(entry.Level.String()))))
+
+	for _, key := range []string{schema.RequestMethod, schema.URI} {
+		if v, ok := entry.Data[key]; ok {
+			b.WriteByte(' ')
+			b.WriteString(fmt.Sprint(v))
+			used[key] = true
+		}
+	}
+	if v, ok := entry.Data[schema.Status]; ok {
+		b.WriteByte(' ')
+		b.WriteString(f.colorize(statusColor(v), fmt.Sprint(v)))
+		used[schema.Status] = true
+	}
+	if v, ok := entry.Data[schema.Code]; ok {
+		b.WriteByte(' ')
+		b.WriteString(f.colorize(levelColor(entry.Level), fmt.Sprint(v)))
+		used[schema.Code] = true
+	}
+	if v, ok := entry.Data[schema.Latency]; ok && schema.Latency != "" {
+		b.WriteByte(' ')
+		b.WriteString(fmt.Sprint(v))
+		used[schema.Latency] = true
+	}
+	if entry.Message != "" {
+		b.WriteByte(' ')
+		b.WriteString(entry.Message)
+	}
+	b.WriteByte('\n')
+
+	steps := make([]int, 0)
+	for key := range entry.Data {
+		if n, ok := stepNumber(key); ok {
+			steps = append(steps, n)
+			used[key] = true
+		}
+	}
+	sort.Ints(steps)
+	for _, n := range steps {
+		fmt.Fprintf(b, "    %s %v\n", f.colorize(colorCyan, strconv.Itoa(n)+"."), entry.Data[stepPrefix+strconv.Itoa(n)])
+	}
+	for _, key := range sortedKeys(entry.Data) {
+		if used[key] {
+			continue
+		}
+		b.WriteString("    ")
+		b.WriteString(f.colorize(colorGray, key))
+		b.WriteByte('=')
+		writeValue(b, entry.Data[key])
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+func (f *ConsoleFormatter) colorize(color int, s string) string {
+	if f.DisableColors {
+		return s
+	}
+	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", color, s)
+}
+
+// AutoFormatter returns a ConsoleFormatter when output is a terminal and a JSONFormatter otherwise.
+func AutoFormatter(output io.Writer) Formatter {
+	if isTerminal(output) {
+		return &ConsoleFormatter{}
+	}
+	return &JSONFormatter{}
+}
+
+func isTerminal(output io.Writer) bool {
+	if w, ok := output.(*lockedWriter); ok {
+		output = w.Writer
+	}
+	f, ok := output.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+func levelColor(level logrus.Level) int {
+	switch level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return colorGray
+	case logrus.WarnLevel:
+		return colorYellow
+	case logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel:
+		return colorRed
+	default:
+		return colorBlue
+	}
+}
+
+func statusColor(status interface{}) int {
+	code, err := strconv.Atoi(fmt.Sprint(status))
+	if err != nil {
+		return colorGray
+	}
+	switch {
+	case code >= 500:
+		return colorRed
+	case code >= 400:
+		return colorYellow
+	case code >= 300:
+		return colorCyan
+	case code >= 200:
+		return colorGreen
+	default:
+		return colorGray
+	}
+}
+
+func stepNumber(key string) (int, bool) {
+	if !strings.HasPrefix(key, stepPrefix) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(key, stepPrefix))
+	return n, err == nil
+}
+
+func sortedKeys(data logrus.Fields) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func entryBuffer(entry *logrus.Entry) *bytes.Buffer {
+	if entry.Buffer != nil {
+		return entry.Buffer
+	}
+	return &bytes.Buffer{}
+}
+
+func appendKeyValue(b *bytes.Buffer, key string, value interface{}) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(logfmtKey(key))
+	b.WriteByte('=')
+	writeValue(b, value)
+}
+
+// logfmtKey removes the characters which are not allowed in a logfmt key.
+func logfmtKey(key string) string {
+	key = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return '_'
+		}
+		return r
+	}, key)
+	if key == "" {
+		return "_"
+	}
+	return key
+}
+
+func writeValue(b *bytes.Buffer, value interface{}) {
+	s := fieldString(value)
+	if needsQuoting(s) {
+		s = strconv.Quote(s)
+	}
+	b.WriteString(s)
+}
+
+// fieldString returns the text of a field value, values which are not strings, numbers or
+// booleans are encoded as json.
+func fieldString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	default:
+		if bs, err := json.Marshal(v); err == nil {
+			return string(bs)
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || !strconv.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestEntry(level logrus.Level, msg string, fields logrus.Fields) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.New()).WithFields(fields)
+	entry.Level = level
+	entry.Message = msg
+	entry.Time = time.Date(2021, 10, 17, 16, 27, 10, 0, time.UTC)
+	return entry
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	entry := newTestEntry(logrus.InfoLevel, "latency: 25µs", logrus.Fields{
+		"STEP_1":      "request name world",
+		StatusField:   200,
+		URIField:      "/hello/world",
+		"empty":       "",
+		"quote":       `say "hi"`,
+		"map":         map[string]string{"a": "b"},
+		"bad key=one": true,
+	})
+	out, err := (&LogfmtFormatter{}).Format(entry)
+	assert.Nil(t, err)
+	expect := `time=2021-10-17T16:27:10Z level=info msg="latency: 25µs" STEP_1="request name world" Status=200 bad_key_one=true empty="" map="{\"a\":\"b\"}" quote="say \"hi\"" uri=/hello/world` + "\n"
+	assert.Equal(t, expect, string(out))
+}
+
+func TestConsoleFormatter(t *testing.T) {
+	entry := newTestEntry(logrus.ErrorLevel, "latency: 25µs", logrus.Fields{
+		"STEP_2":           "second",
+		"STEP_10":          "tenth",
+		"STEP_1":           "first",
+		StatusField:        500,
+		URIField:           "/hello/world",
+		RequestMethodField: "GET",
+		ClientIPField:      "::1",
+	})
+	out, err := (&ConsoleFormatter{DisableColors: true}).Format(entry)
+	assert.Nil(t, err)
+	t.Logf("Log output\n%v", string(out))
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	assert.Equal(t, []string{
+		"16:27:10.000 ERROR GET /hello/world 500 latency: 25µs",
+		"    1. first",
+		"    2. second",
+		"    10. tenth",
+		"    client_ip=::1",
+	}, lines)
+
+	out, err = (&ConsoleFormatter{}).Format(entry)
+	assert.Nil(t, err)
+	assert.Contains(t, string(out), "\x1b[31m500\x1b[0m")
+}
+
+func TestAutoFormatter(t *testing.T) {
+	_, ok := AutoFormatter(&bytes.Buffer{}).(*JSONFormatter)
+	assert.True(t, ok, "expected json formatter for a buffer")
+
+	f, err := os.CreateTemp(t.TempDir(), "log")
+	assert.Nil(t, err)
+	defer f.Close()
+	_, ok = AutoFormatter(f).(*JSONFormatter)
+	assert.True(t, ok, "expected json formatter for a regular file")
+}
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TraceIDField      = "trace_id"
+	SpanIDField       = "span_id"
+	TraceSampledField = "trace_sampled"
+
+	gcpHTTPRequestPrefix = "httpRequest."
+)
+
+// GCPFormatter formats logs into Google Cloud Logging structured json. The request fields of
+// the middlewares are nested into the httpRequest object, the other fields are kept at the
+// top level of the jsonPayload.
+type GCPFormatter struct {
+	// ProjectID is the Google Cloud project of the trace, the trace is logged as
+	// projects/ProjectID/traces/TRACE_ID. The trace id is logged as is when it is empty.
+	ProjectID string
+
+	// Schema defines the field names used by the middlewares. Default is DefaultSchema, the
+	// fields of GCPSchema, prefixed by httpRequest, are always nested into httpRequest. The
+	// latency is only logged by a schema with a Latency field, such as GCPSchema.
+	Schema Schema
+
+	// TraceContext returns the trace context of the entry. When it is nil the trace context is
+	// read from the TraceID, SpanID and TraceSampled fields of the schema, logged by the
+	// middlewares from the traceparent and X-Cloud-Trace-Context headers.
+	TraceContext func(entry *logrus.Entry) (traceID, spanID string, sampled bool)
+}
+
+// severity maps a logrus level to a Cloud Logging severity.
+func severity(level logrus.Level) string {
+	switch level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return "DEBUG"
+	case logrus.InfoLevel:
+		return "INFO"
+	case logrus.WarnLevel:
+		return "WARNING"
+	case logrus.ErrorLevel:
+		return "ERROR"
+	case logrus.FatalLevel:
+		return "CRITICAL"
+	case logrus.PanicLevel:
+		return "ALERT"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// gcpDuration formats a latency as a google.protobuf.Duration json string, e.g. "0.123s".
+func gcpDuration(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Duration:
+		return strconv.FormatFloat(v.Seconds(), 'f', -1, 64) + "s"
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+		}
+	}
+	return value
+}
+
+// Format renders a single log entry
+func (f *GCPFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	schema := f.Schema.withDefaults()
+	httpFields := map[string]string{
+		schema.RequestMethod: "requestMethod",
+		schema.URI:           "requestUrl",
+		schema.Status:        "status",
+		schema.UserAgent:     "userAgent",
+		schema.ClientIP:      "remoteIp",
+		schema.RequestSize:   "requestSize",
+		schema.ResponseSize:  "responseSize",
+		schema.Protocol:      "protocol",
+	}
+	if schema.Latency != "" {
+		httpFields[schema.Latency] = "latency"
+	}
+
+	var (
+		data        = make(logrus.Fields, len(entry.Data)+6)
+		httpRequest = make(map[string]interface{})
+	)
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		name, ok := httpFields[k]
+		if !ok && strings.HasPrefix(k, gcpHTTPRequestPrefix) {
+			name, ok = strings.TrimPrefix(k, gcpHTTPRequestPrefix), true
+		}
+		if !ok {
+			data[k] = v
+			continue
+		}
+		if name == "latency" {
+			v = gcpDuration(v)
+		}
+		httpRequest[name] = v
+	}
+	if len(httpRequest) > 0 {
+		data["httpRequest"] = httpRequest
+	}
+
+	traceID, spanID, sampled := f.traceContext(schema, entry)
+	if traceID != "" {
+		delete(data, schema.TraceID)
+		delete(data, schema.SpanID)
+		delete(data, schema.TraceSampled)
+		if f.ProjectID != "" {
+			traceID = fmt.Sprintf("projects/%s/traces/%s", f.ProjectID, traceID)
+		}
+		data["logging.googleapis.com/trace"] = traceID
+		if spanID != "" {
+			data["logging.googleapis.com/spanId"] = spanID
+		}
+		data["logging.googleapis.com/trace_sampled"] = sampled
+	}
+
+	if entry.HasCaller() {
+		data["logging.googleapis.com/sourceLocation"] = map[string]interface{}{
+			"file":     entry.Caller.File,
+			"line":     strconv.Itoa(entry.Caller.Line),
+			"function": entry.Caller.Function,
+		}
+	}
+	data["severity"] = severity(entry.Level)
+	data["message"] = entry.Message
+	data["timestamp"] = entry.Time.Format(time.RFC3339Nano)
+
+	var b *bytes.Buffer
+	if entry.Buffer != nil {
+		b = entry.Buffer
+	} else {
+		b = &bytes.Buffer{}
+	}
+	if err := json.NewEncoder(b).Encode(data); err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func (f *GCPFormatter) traceContext(schema Schema, entry *logrus.Entry) (traceID, spanID string, sampled bool) {
+	if f.TraceContext != nil {
+		return f.TraceContext(entry)
+	}
+	traceID, _ = entry.Data[schema.TraceID].(string)
+	spanID, _ = entry.Data[schema.SpanID].(string)
+	sampled, _ = entry.Data[schema.TraceSampled].(bool)
+	return
+}
@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGCPFormatter(t *testing.T) {
+	tests := []struct {
+		name      string
+		formatter *GCPFormatter
+		level     logrus.Level
+		fields    logrus.Fields
+		expect    map[string]interface{}
+	}{
+		{
+			name:      "Test severity and message",
+			formatter: &GCPFormatter{},
+			level:     logrus.WarnLevel,
+			fields:    logrus.Fields{"K1": "V1", "err": errors.New("test err")},
+			expect: map[string]interface{}{
+				"severity": "WARNING",
+				"message":  "hello",
+				"K1":       "V1",
+				"err":      "test err",
+			},
+		},
+		{
+			name:      "Test http request with gcp schema",
+			formatter: &GCPFormatter{},
+			level:     logrus.InfoLevel,
+			fields: logrus.Fields{
+				"httpRequest.requestMethod": "GET",
+				"httpRequest.status":        200,
+				"httpRequest.latency":       123 * time.Millisecond,
+				"httpRequest.protocol":      "HTTP/1.1",
+			},
+			expect: map[string]interface{}{
+				"severity": "INFO",
+				"httpRequest": map[string]interface{}{
+					"requestMethod": "GET",
+					"status":        float64(200),
+					"latency":       "0.123s",
+					"protocol":      "HTTP/1.1",
+				},
+			},
+		},
+		{
+			name:      "Test http request with default schema and trace",
+			formatter: &GCPFormatter{ProjectID: "my-project", Schema: DefaultSchema},
+			level:     logrus.ErrorLevel,
+			fields: logrus.Fields{
+				URIField:          "/hello",
+				ClientIPField:     "10.0.0.1",
+				StatusField:       500,
+				TraceIDField:      "abc",
+				SpanIDField:       "def",
+				TraceSampledField: true,
+			},
+			expect: map[string]interface{}{
+				"severity": "ERROR",
+				"httpRequest": map[string]interface{}{
+					"requestUrl": "/hello",
+					"remoteIp":   "10.0.0.1",
+					"status":     float64(500),
+				},
+				"logging.googleapis.com/trace":         "projects/my-project/traces/abc",
+				"logging.googleapis.com/spanId":        "def",
+				"logging.googleapis.com/trace_sampled": true,
+			},
+		},
+		{
+			name: "Test trace context function",
+			formatter: &GCPFormatter{TraceContext: func(*logrus.Entry) (string, string, bool) {
+				return "trace", "span", false
+			}},
+			level: logrus.DebugLevel,
+			expect: map[string]interface{}{
+				"severity":                             "DEBUG",
+				"logging.googleapis.com/trace":         "trace",
+				"logging.googleapis.com/spanId":        "span",
+				"logging.googleapis.com/trace_sampled": false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := logrus.NewEntry(logrus.New()).WithFields(tt.fields)
+			entry.Level = tt.level
+			entry.Message = "hello"
+			entry.Time = time.Now()
+			out, err := tt.formatter.Format(entry)
+			assert.Nil(t, err)
+			t.Logf("Log output %v", string(out))
+
+			var data map[string]interface{}
+			if err := json.Unmarshal(out, &data); err != nil {
+				t.Error("unexpected error", err)
+			}
+			for k, v := range tt.expect {
+				assert.Equal(t, v, data[k], "field %v", k)
+			}
+			_, ok := data["timestamp"]
+			assert.True(t, ok, `cannot found expected "timestamp" field: %v`, data)
+		})
+	}
+}
+
+func TestGCPFormatterSourceLocation(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := logrus.New()
+	l.SetOutput(buf)
+	l.SetFormatter(&GCPFormatter{})
+	l.SetReportCaller(true)
+	l.Info("hello")
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	location, ok := data["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	assert.True(t, ok, `cannot found expected "sourceLocation" field: %v`, data)
+	assert.Contains(t, location["function"], "TestGCPFormatterSourceLocation")
+}
+
+func TestGCPFormatterMiddleware(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithOutput(buf))
+	server := gin.New()
+	server.Use(GinMiddleware(ConfigGin{Schema: GCPSchema, Formatter: &GCPFormatter{}}))
+	server.GET("/hello/:name", func(ctx *gin.Context) {
+		ctx.String(200, "hello")
+	})
+	performRequest(server, "GET", "/hello/world")
+	t.Logf("Log output %v", buf.String())
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	httpRequest, ok := data["httpRequest"].(map[string]interface{})
+	assert.True(t, ok, `cannot found expected "httpRequest" field: %v`, data)
+	assert.Equal(t, "GET", httpRequest["requestMethod"])
+	assert.Equal(t, "/hello/world", httpRequest["requestUrl"])
+	assert.Equal(t, float64(200), httpRequest["status"])
+	assert.Regexp(t, `^[0-9.]+s$`, httpRequest["latency"])
+	assert.Equal(t, "INFO", data["severity"])
+}
+
+func TestGCPFormatterDefaultSchema(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithOutput(buf))
+	server := gin.New()
+	server.Use(GinMiddleware(ConfigGin{Formatter: &GCPFormatter{ProjectID: "my-project"}}))
+	server.GET("/hello/:name", func(ctx *gin.Context) {
+		ctx.String(200, "hello")
+	})
+	req := httptest.NewRequest("GET", "/hello/world", nil)
+	req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	server.ServeHTTP(httptest.NewRecorder(), req)
+	t.Logf("Log output %v", buf.String())
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	httpRequest, ok := data["httpRequest"].(map[string]interface{})
+	assert.True(t, ok, `cannot found expected "httpRequest" field: %v`, data)
+	assert.Equal(t, "GET", httpRequest["requestMethod"])
+	assert.Equal(t, "/hello/world", httpRequest["requestUrl"])
+	assert.Equal(t, float64(200), httpRequest["status"])
+	assert.Equal(t, "HTTP/1.1", httpRequest["protocol"])
+	assert.NotContains(t, httpRequest, "latency")
+	assert.Equal(t, "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736", data["logging.googleapis.com/trace"])
+	assert.Equal(t, "00f067aa0ba902b7", data["logging.googleapis.com/spanId"])
+	assert.Equal(t, true, data["logging.googleapis.com/trace_sampled"])
+	assert.NotContains(t, data, TraceIDField)
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
 	"time"
 )
 
@@ -11,13 +14,58 @@ var DefaultConfigGin = ConfigGin{
 	SkipperGin: DefaultSkipperGin,
 }
 
+// DefaultGinErrorLevels logs the request at error level for every gin error type.
+var DefaultGinErrorLevels = map[gin.ErrorType]logrus.Level{
+	gin.ErrorTypeAny: logrus.ErrorLevel,
+}
+
+// ginErrorTypes are the names of the gin error types.
+var ginErrorTypes = []struct {
+	errorType gin.ErrorType
+	name      string
+}{
+	{gin.ErrorTypeBind, "bind"},
+	{gin.ErrorTypeRender, "render"},
+	{gin.ErrorTypePrivate, "private"},
+	{gin.ErrorTypePublic, "public"},
+}
+
+// GinErrorLog is the log of a gin error.
+type GinErrorLog struct {
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Meta    interface{} `json:"meta,omitempty"`
+}
+
 func GinMiddleware(config ConfigGin) gin.HandlerFunc {
 	if config.SkipperGin == nil {
 		config.SkipperGin = DefaultSkipperGin
 	}
+	if config.Formatter == nil {
+		config.Formatter = AutoFormatter(logrus.StandardLogger().Out)
+	}
+	if config.ErrorTypes == 0 {
+		config.ErrorTypes = gin.ErrorTypeAny
+	}
+	if config.ErrorLevels == nil {
+		config.ErrorLevels = DefaultGinErrorLevels
+	}
+	if config.Levels == nil {
+		config.Levels = DefaultLevelRegistry
+	}
+	if config.Metrics == nil {
+		config.Metrics = noMetrics{}
+	}
+	if config.ErrorFormatter == nil {
+		config.ErrorFormatter = DefaultErrorFormatter
+	}
+	schema := config.Schema.withDefaults()
+	capture := newCapturer(config.Capture, schema)
+	debug := newDebugger(config.Debug)
+	accessLog := newAccessLogger(config.AccessLog)
 
 	return func(ctx *gin.Context) {
-		logger := New(WithFormatter(&JSONFormatter{}))
+		logger := New(WithFormatter(config.Formatter))
 		if config.SkipperGin(ctx) {
 			ctx.Next()
 			return
@@ -32,43 +80,175 @@ func GinMiddleware(config ConfigGin) gin.HandlerFunc {
 
 			userAgent = ctx.Request.UserAgent()
 			uri       = ctx.Request.RequestURI
-			errs      string
+			route     = ctx.FullPath()
 			start     = time.Now()
-			isError   bool
+			level     = logrus.InfoLevel
 		)
+		defer config.Metrics.TrackHTTP(method)()
+		if config.ClientIP != nil {
+			clientIP = config.ClientIP.Resolve(ctx.Request.RemoteAddr, ctx.Request.Header)
+		}
 
 		logger.WithFields(map[string]interface{}{
-			ClientIPField:      clientIP,
-			RequestMethodField: method,
-			UserAgentField:     userAgent,
-			URIField:           uri,
+			schema.ClientIP:      clientIP,
+			schema.RemoteAddr:    ctx.Request.RemoteAddr,
+			schema.RequestMethod: method,
+			schema.UserAgent:     userAgent,
+			schema.URI:           uri,
+			schema.Route:         routeLabel(route),
+			schema.Path:          ctx.Request.URL.Path,
 		})
+		if query := ctx.Request.URL.RawQuery; query != "" {
+			logger.WithField(schema.Query, query)
+		}
+		logger.WithFields(httpFields(schema, ctx.Request, httpScheme(ctx.Request)))
+		if capture != nil {
+			logger.WithFields(capture.requestFields(ctx.Request.Header, ctx.Request.URL.Query()))
+		}
+		logger.SetLevel(config.Levels.Level(method, route))
+		var body *bodyBuffer
+		if token := ctx.GetHeader(debug.headerName()); debug.verify(token) {
+			logger.forceDebug(debug, token)
+			logger.WithField(schema.Request, debug.readBody(ctx.Request))
+			writer := &ginBodyWriter{ResponseWriter: ctx.Writer, body: &bodyBuffer{max: debug.config.MaxBodySize}}
+			ctx.Writer, body = writer, writer.body
+		}
 		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), Key, logger))
 		ctx.Set(Key, logger)
-		ctx.Next()
+		p, _ := config.Recovery.call(func() error {
+			ctx.Next()
+			return nil
+		})
+		if p != nil && !config.Recovery.RePanic && !ctx.Writer.Written() {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+		}
 		var (
 			statusCode = ctx.Writer.Status()
 		)
-		logger.WithField(StatusField, statusCode)
-		if ctx.Errors != nil {
-			isError = true
-			bs, err := ctx.Errors.MarshalJSON()
-			if err == nil {
-				errs = string(bs)
-			} else {
-				errs = ctx.Errors.String()
-			}
+		if p != nil {
+			statusCode = http.StatusInternalServerError
+			logger.recordPanic(schema, p)
 		}
-		if len(errs) > 0 {
-			logger.WithField(ErrorsField, errs)
+		logger.WithFields(map[string]interface{}{
+			schema.Status:       statusCode,
+			schema.ResponseSize: ginResponseSize(ctx.Writer),
+		})
+		if capture != nil {
+			logger.WithFields(capture.responseFields(ctx.Writer.Header()))
+		}
+		if body != nil {
+			logger.WithField(schema.Response, body.String())
+		}
+		if errs := ctx.Errors.ByType(config.ErrorTypes); len(errs) > 0 {
+			level = ginErrorsLevel(errs, config.ErrorLevels)
+			logger.withError(config.ErrorFormatter, schema, ginErrors(errs))
+			logger.WithField(schema.Errors, ginErrorLogs(errs))
 		}
 		end := time.Now()
-		logger.WithField(EndField, end)
+		logger.WithField(schema.End, end)
+		if schema.Latency != "" {
+			logger.WithField(schema.Latency, end.Sub(start))
+		}
 		msg := fmt.Sprintf("latency: %v", end.Sub(start))
-		if isError {
-			logger.Error(msg)
-		} else {
-			logger.Info(msg)
+		logger.finish(msg, level, p, config.Recovery)
+		config.Metrics.ObserveHTTP(method, routeLabel(route), statusCode, end.Sub(start))
+		if accessLog != nil {
+			user, _, _ := ctx.Request.BasicAuth()
+			accessLog.write(accessLogEntry{
+				host:      clientIP,
+				user:      user,
+				time:      start,
+				method:    method,
+				uri:       uri,
+				proto:     ctx.Request.Proto,
+				status:    statusCode,
+				size:      int64(ctx.Writer.Size()),
+				referer:   ctx.Request.Referer(),
+				userAgent: userAgent,
+				requestID: ctx.Request.Header.Get(accessLog.config.RequestIDHeader),
+				latency:   end.Sub(start),
+			})
+		}
+		if p != nil && config.Recovery.RePanic {
+			panic(p.value)
 		}
 	}
 }
+
+// ginErrors returns the errors of the context as one error.
+func ginErrors(errs []*gin.Error) error {
+	if len(errs) == 1 {
+		return errs[0].Err
+	}
+	joined := make(joinedErrors, 0, len(errs))
+	for _, err := range errs {
+		joined = append(joined, err.Err)
+	}
+	return joined
+}
+
+// ginErrorTypeName returns the names of the types of a gin error, e.g. "bind|public".
+func ginErrorTypeName(errorType gin.ErrorType) string {
+	names := make([]string, 0, 1)
+	for _, t := range ginErrorTypes {
+		if errorType&t.errorType != 0 {
+			names = append(names, t.name)
+		}
+	}
+	if len(names) == 0 {
+		return "unknown"
+	}
+	return strings.Join(names, "|")
+}
+
+// ginErrorLogs returns the logs of the errors of the context.
+func ginErrorLogs(errs []*gin.Error) []GinErrorLog {
+	logs := make([]GinErrorLog, 0, len(errs))
+	for _, err := range errs {
+		logs = append(logs, GinErrorLog{
+			Message: err.Error(),
+			Type:    ginErrorTypeName(err.Type),
+			Meta:    err.Meta,
+		})
+	}
+	return logs
+}
+
+// ginErrorsLevel returns the worst level of the errors of the context, info level when no level
+// matches their types.
+func ginErrorsLevel(errs []*gin.Error, levels map[gin.ErrorType]logrus.Level) logrus.Level {
+	level := logrus.InfoLevel
+	for _, err := range errs {
+		for errorType, l := range levels {
+			if err.Type&errorType != 0 && l < level {
+				level = l
+			}
+		}
+	}
+	return level
+}
+
+// ginBodyWriter is a gin.ResponseWriter keeping the first MaxBodySize bytes written.
+type ginBodyWriter struct {
+	gin.ResponseWriter
+	body *bodyBuffer
+}
+
+func (w *ginBodyWriter) Write(b []byte) (int, error) {
+	w.body.keep(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *ginBodyWriter) WriteString(s string) (int, error) {
+	w.body.keep([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// ginResponseSize returns the number of bytes of the response body written, Size is -1 when
+// nothing is written.
+func ginResponseSize(w gin.ResponseWriter) int {
+	if size := w.Size(); size > 0 {
+		return size
+	}
+	return 0
+}
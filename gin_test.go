@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"net/http"
@@ -139,3 +140,80 @@ func TestGinMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestGinMiddlewareErrors(t *testing.T) {
+	var (
+		privateErr = &gin.Error{Err: errors.New("db timeout"), Type: gin.ErrorTypePrivate, Meta: map[string]interface{}{"table": "users"}}
+		publicErr  = &gin.Error{Err: errors.New("invalid name"), Type: gin.ErrorTypePublic}
+		bindErr    = &gin.Error{Err: errors.New("bad json"), Type: gin.ErrorTypeBind | gin.ErrorTypePublic}
+		levels     = map[gin.ErrorType]logrus.Level{
+			gin.ErrorTypePrivate: logrus.ErrorLevel,
+			gin.ErrorTypePublic:  logrus.WarnLevel,
+		}
+	)
+	tests := []struct {
+		name     string
+		config   ConfigGin
+		errs     []*gin.Error
+		logLevel string
+		expect   interface{}
+	}{
+		{
+			name:     "Test default config",
+			config:   DefaultConfigGin,
+			errs:     []*gin.Error{privateErr, bindErr},
+			logLevel: "error",
+			expect: []interface{}{
+				map[string]interface{}{"message": "db timeout", "type": "private", "meta": map[string]interface{}{"table": "users"}},
+				map[string]interface{}{"message": "bad json", "type": "bind|public"},
+			},
+		},
+		{
+			name:     "Test public errors only warn",
+			config:   ConfigGin{ErrorLevels: levels},
+			errs:     []*gin.Error{publicErr},
+			logLevel: "warning",
+			expect: []interface{}{
+				map[string]interface{}{"message": "invalid name", "type": "public"},
+			},
+		},
+		{
+			name:     "Test worst error level",
+			config:   ConfigGin{ErrorLevels: levels},
+			errs:     []*gin.Error{publicErr, privateErr},
+			logLevel: "error",
+			expect: []interface{}{
+				map[string]interface{}{"message": "invalid name", "type": "public"},
+				map[string]interface{}{"message": "db timeout", "type": "private", "meta": map[string]interface{}{"table": "users"}},
+			},
+		},
+		{
+			name:     "Test filter error types",
+			config:   ConfigGin{ErrorTypes: gin.ErrorTypePrivate},
+			errs:     []*gin.Error{publicErr},
+			logLevel: "info",
+			expect:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+			server := gin.New()
+			server.Use(GinMiddleware(tt.config))
+			server.GET("/hello", func(ctx *gin.Context) {
+				ctx.Errors = append(ctx.Errors, tt.errs...)
+				ctx.Status(http.StatusBadRequest)
+			})
+			performRequest(server, "GET", "/hello")
+
+			t.Logf("Log output %v", buf.String())
+			var data map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+				t.Error("unexpected error", err)
+			}
+			assert.Equal(t, tt.logLevel, data[FieldKeyLevel])
+			assert.Equal(t, tt.expect, data[ErrorsField])
+		})
+	}
+}
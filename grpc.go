@@ -3,10 +3,14 @@ package logger
 import (
 	"context"
 	"fmt"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"time"
 )
 
@@ -18,9 +22,24 @@ func GrpcInterceptor(config ConfigGrpc) grpc.UnaryServerInterceptor {
 	if config.SkipperGrpc == nil {
 		config.SkipperGrpc = DefaultSkipperGrpc
 	}
+	if config.Formatter == nil {
+		config.Formatter = AutoFormatter(logrus.StandardLogger().Out)
+	}
+	if config.Levels == nil {
+		config.Levels = DefaultLevelRegistry
+	}
+	if config.Metrics == nil {
+		config.Metrics = noMetrics{}
+	}
+	if config.ErrorFormatter == nil {
+		config.ErrorFormatter = DefaultErrorFormatter
+	}
+	schema := config.Schema.withDefaults()
+	capture := newCapturer(config.Capture, schema)
+	debug := newDebugger(config.Debug)
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		log := New(WithFormatter(&JSONFormatter{}))
+		log := New(WithFormatter(config.Formatter))
 		if config.SkipperGrpc(ctx, info) {
 			return handler(ctx, req)
 		}
@@ -29,19 +48,55 @@ func GrpcInterceptor(config ConfigGrpc) grpc.UnaryServerInterceptor {
 			config.BeforeFuncGrpc(ctx, info)
 		}
 		var (
-			start   = time.Now()
-			isError bool
+			start     = time.Now()
+			level     = logrus.InfoLevel
+			recovered *panicInfo
 		)
+		service, method := splitFullMethod(info.FullMethod)
+		defer config.Metrics.TrackGrpc(service, method)()
 
+		md, _ := metadata.FromIncomingContext(ctx)
 		p, ok := peer.FromContext(ctx)
 		if ok {
-			log.WithField(ClientIPField, p.Addr.String())
+			clientIP := p.Addr.String()
+			if config.ClientIP != nil {
+				clientIP = config.ClientIP.Resolve(p.Addr.String(), md)
+			}
+			log.WithFields(map[string]interface{}{
+				schema.ClientIP:   clientIP,
+				schema.RemoteAddr: p.Addr.String(),
+			})
+			if p.AuthInfo != nil {
+				log.WithField(schema.AuthType, p.AuthInfo.AuthType())
+			}
+			if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+				log.WithFields(tlsFields(schema, &tlsInfo.State))
+			}
+		}
+		if size, ok := messageSize(req); ok {
+			log.WithField(schema.RequestSize, size)
 		}
 		log.WithFields(map[string]interface{}{
-			StartField:   start,
-			URIField:     info.FullMethod,
-			RequestField: req,
+			schema.Start:       start,
+			schema.URI:         info.FullMethod,
+			schema.Route:       info.FullMethod,
+			schema.GrpcService: service,
+			schema.GrpcMethod:  method,
+			schema.Request:     req,
 		})
+		if authority := md.Get(":authority"); len(authority) > 0 {
+			log.WithField(schema.Authority, authority[0])
+		}
+		if userAgent := md.Get("user-agent"); len(userAgent) > 0 {
+			log.WithField(schema.UserAgent, userAgent[0])
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			log.WithField(schema.Deadline, time.Until(deadline))
+		}
+		log.WithFields(traceFields(schema, metadataValue(md)))
+		if capture != nil {
+			log.WithFields(capture.requestFields(md, nil))
+		}
 
 		defer func() {
 			var (
@@ -52,23 +107,68 @@ func GrpcInterceptor(config ConfigGrpc) grpc.UnaryServerInterceptor {
 			if err != nil {
 				code = status.Code(err)
 			}
-			log.WithField(CodeField, code.String())
+			log.WithField(schema.Code, code.String())
+			if schema.Latency != "" {
+				log.WithField(schema.Latency, end.Sub(start))
+			}
 			msg := fmt.Sprintf("latency: %v", end.Sub(start))
-			if isError {
-				log.Error(msg)
-			} else {
-				log.Info(msg)
+			log.finish(msg, level, recovered, config.Recovery)
+			config.Metrics.ObserveGrpc(service, method, code.String(), end.Sub(start))
+			if recovered != nil && config.Recovery.RePanic {
+				panic(recovered.value)
 			}
 		}()
 
+		log.SetLevel(config.Levels.Level("", info.FullMethod))
+		if token := debug.metadataToken(ctx); debug.verify(token) {
+			log.forceDebug(debug, token)
+		}
 		ctx = context.WithValue(ctx, Key, log)
-		resp, err = handler(ctx, req)
+		recovered, err = config.Recovery.call(func() (err error) {
+			resp, err = handler(ctx, req)
+			return err
+		})
+		if recovered != nil {
+			log.recordPanic(schema, recovered)
+			err = status.Error(codes.Internal, recovered.Error())
+		}
+		// The client cancelled the request or its deadline is exceeded. A client whose deadline is
+		// exceeded resets the stream, which may cancel the context before the deadline of the
+		// server is reached.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			log.WithField(schema.ContextError, ctxErr.Error())
+		}
 		if err != nil {
-			isError = true
-			log.WithField(ErrorsField, err)
+			level = logrus.ErrorLevel
+			log.withError(config.ErrorFormatter, schema, err)
+			if s, ok := status.FromError(err); ok {
+				log.WithFields(statusFields(schema, s))
+			}
 		} else {
-			log.WithField(ResponseField, resp)
+			log.WithField(schema.Response, resp)
+			if size, ok := messageSize(resp); ok {
+				log.WithField(schema.ResponseSize, size)
+			}
 		}
 		return
 	}
 }
+
+// messageSize returns the size of the wire encoding of a protobuf message.
+func messageSize(message interface{}) (int, bool) {
+	m, ok := message.(proto.Message)
+	if !ok {
+		return 0, false
+	}
+	return proto.Size(m), true
+}
+
+// metadataValue returns a function reading the first value of a metadata key.
+func metadataValue(md metadata.MD) func(name string) string {
+	return func(name string) string {
+		if values := md.Get(name); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+}
@@ -7,13 +7,16 @@ import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	pb "github.com/trinhdaiphuc/logger/proto/hello"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 	"net"
+	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGrpcInterceptor(t *testing.T) {
@@ -136,6 +139,28 @@ func (h HelloService) Hello(ctx context.Context, request *pb.HelloRequest) (*pb.
 	if len(request.Name) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "empty name")
 	}
+	if request.Name == "panic" {
+		panic("hello panic")
+	}
+	if request.Name == "debug" {
+		logger.AddDebugLog("debug step")
+	}
+	if request.Name == "unknown" {
+		return nil, WrapError(status.Error(codes.NotFound, "unknown name")).With("name", request.Name)
+	}
+	if request.Name == "slow" {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if request.Name == "details" {
+		s, _ := status.New(codes.InvalidArgument, "invalid name").WithDetails(
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "name", Description: "must not be details"},
+			}},
+			&errdetails.ErrorInfo{Reason: "NAME_RESERVED", Domain: "hello", Metadata: map[string]string{"name": request.Name}},
+		)
+		return nil, s.Err()
+	}
 	return &pb.HelloResponse{Message: "Hello " + request.Name}, nil
 }
 
@@ -160,3 +185,74 @@ func dialer(config ConfigGrpc) func(context.Context, string) (net.Conn, error) {
 		return listener.Dial()
 	}
 }
+
+// chanWriter sends the lines written to the channel.
+type chanWriter chan []byte
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func TestGrpcInterceptorEnrichment(t *testing.T) {
+	lines := make(chanWriter, 1)
+	New(WithFormatter(&JSONFormatter{}), WithOutput(lines))
+	defer New(WithOutput(os.Stderr))
+	conn, err := grpc.DialContext(context.Background(), "hello.example.com", grpc.WithInsecure(),
+		grpc.WithContextDialer(dialer(ConfigGrpc{})), grpc.WithUserAgent("hello-client/1.0"))
+	assert.Nil(t, err)
+	defer conn.Close()
+	client := pb.NewHelloServiceClient(conn)
+	decode := func() map[string]interface{} {
+		var data map[string]interface{}
+		assert.Nil(t, json.Unmarshal(<-lines, &data))
+		return data
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = client.Hello(ctx, &pb.HelloRequest{Name: "slow"})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	data := decode()
+	assert.Equal(t, "hello.HelloService", data[GrpcServiceField])
+	assert.Equal(t, "Hello", data[GrpcMethodField])
+	assert.Equal(t, "hello.example.com", data[AuthorityField])
+	assert.Contains(t, data[UserAgentField], "hello-client/1.0")
+	deadline, _ := data[DeadlineField].(float64)
+	assert.True(t, deadline > 0 && time.Duration(deadline) <= 100*time.Millisecond, "unexpected deadline %v", deadline)
+	assert.Contains(t, []interface{}{context.DeadlineExceeded.Error(), context.Canceled.Error()}, data[ContextErrorField])
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err = client.Hello(ctx, &pb.HelloRequest{Name: "slow"})
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	data = decode()
+	assert.Equal(t, context.Canceled.Error(), data[ContextErrorField])
+	_, ok := data[DeadlineField]
+	assert.False(t, ok, `unexpected "%v" field: %v`, DeadlineField, data)
+
+	_, err = client.Hello(context.Background(), &pb.HelloRequest{Name: "details"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	data = decode()
+	assert.Equal(t, "invalid name", data[ErrorMessageField])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"@type":           "type.googleapis.com/google.rpc.BadRequest",
+			"fieldViolations": []interface{}{map[string]interface{}{"field": "name", "description": "must not be details"}},
+		},
+		map[string]interface{}{
+			"@type":    "type.googleapis.com/google.rpc.ErrorInfo",
+			"reason":   "NAME_RESERVED",
+			"domain":   "hello",
+			"metadata": map[string]interface{}{"name": "details"},
+		},
+	}, data[ErrorDetailsField])
+	assert.Equal(t, "NAME_RESERVED", data[ErrorReasonField])
+	assert.Equal(t, "hello", data[ErrorDomainField])
+	assert.Equal(t, []interface{}{map[string]interface{}{"field": "name", "description": "must not be details"}}, data[FieldErrorsField])
+	_, ok = data[ContextErrorField]
+	assert.False(t, ok, `unexpected "%v" field: %v`, ContextErrorField, data)
+}
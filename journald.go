@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultJournaldAddress is the native socket of systemd-journald.
+	DefaultJournaldAddress = "/run/systemd/journal/socket"
+
+	journaldMaxFieldName = 64
+)
+
+// JournaldFormatter formats the logs into the native protocol of systemd-journald. The fields
+// are journal fields with upper case names, e.g. request_method is REQUEST_METHOD. Use it with a
+// JournaldWriter.
+type JournaldFormatter struct {
+	// Identifier is the SYSLOG_IDENTIFIER of the logs. Default is the name of the executable.
+	Identifier string
+
+	// Formatter formats the MESSAGE of the logs, e.g. a JSONFormatter sends the whole request line.
+	// Default is the message of the log.
+	Formatter Formatter
+
+	once sync.Once
+}
+
+// Format renders a single log entry
+func (f *JournaldFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.once.Do(func() {
+		if f.Identifier == "" {
+			f.Identifier = filepath.Base(os.Args[0])
+		}
+	})
+	msg, err := formatMessage(f.Formatter, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &bytes.Buffer{}
+	journaldField(b, "MESSAGE", msg)
+	journaldField(b, "PRIORITY", strconv.Itoa(syslogSeverity(entry.Level)))
+	journaldField(b, "SYSLOG_IDENTIFIER", f.Identifier)
+	if entry.HasCaller() {
+		journaldField(b, "CODE_FILE", entry.Caller.File)
+		journaldField(b, "CODE_LINE", strconv.Itoa(entry.Caller.Line))
+		journaldField(b, "CODE_FUNC", entry.Caller.Function)
+	}
+	for _, key := range sortedKeys(entry.Data) {
+		journaldField(b, journaldFieldName(key), fieldString(entry.Data[key]))
+	}
+	return b.Bytes(), nil
+}
+
+// journaldField writes a field as NAME=value, or with the length of the value when it has new lines.
+func journaldField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journaldFieldName returns a journal field name of upper case letters, digits and underscores,
+// which does not start with an underscore, reserved to trusted fields, or a digit.
+func journaldFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "FIELD_" + name
+	}
+	if len(name) > journaldMaxFieldName {
+		name = name[:journaldMaxFieldName]
+	}
+	return name
+}
+
+// JournaldWriter sends every log written to systemd-journald. It is safe for concurrent use.
+type JournaldWriter struct {
+	socket *socketWriter
+}
+
+// NewJournaldWriter connects to the native socket of journald at address, DefaultJournaldAddress
+// when it is empty.
+func NewJournaldWriter(address string) (*JournaldWriter, error) {
+	if address == "" {
+		address = DefaultJournaldAddress
+	}
+	socket := &socketWriter{network: "unixgram", address: address}
+	if err := socket.connect(); err != nil {
+		return nil, err
+	}
+	return &JournaldWriter{socket: socket}, nil
+}
+
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	return w.socket.Write(p)
+}
+
+// Close closes the connection.
+func (w *JournaldWriter) Close() error {
+	return w.socket.Close()
+}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournaldFormatter(t *testing.T) {
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(logrus.Fields{
+		URIField:    "/hello",
+		"_trusted":  "no",
+		"2xx":       true,
+		StackField:  "main.main\n\tmain.go:1",
+		"user.name": "john",
+	})
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "latency: 1ms"
+
+	line, err := (&JournaldFormatter{Identifier: "app"}).Format(entry)
+	assert.Nil(t, err)
+
+	stack := &bytes.Buffer{}
+	stack.WriteString("STACK\n")
+	_ = binary.Write(stack, binary.LittleEndian, uint64(len("main.main\n\tmain.go:1")))
+	stack.WriteString("main.main\n\tmain.go:1\n")
+	assert.Equal(t, "MESSAGE=latency: 1ms\n"+
+		"PRIORITY=3\n"+
+		"SYSLOG_IDENTIFIER=app\n"+
+		"FIELD_2XX=true\n"+
+		"TRUSTED=no\n"+
+		stack.String()+
+		"URI=/hello\n"+
+		"USER_NAME=john\n",
+		string(line))
+}
+
+func TestJournaldWriter(t *testing.T) {
+	dir, err := os.MkdirTemp("", "journald")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	address := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: address, Net: "unixgram"})
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	w, err := NewJournaldWriter(address)
+	assert.Nil(t, err)
+	defer w.Close()
+	New(WithSinks(Sink{Output: w, Formatter: &JournaldFormatter{Identifier: "app"}}))
+	defer New(WithSinks(), WithOutput(os.Stderr))
+	New().WithField(URIField, "/hello").Info("latency: 1ms")
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "MESSAGE=latency: 1ms\nPRIORITY=6\nSYSLOG_IDENTIFIER=app\nURI=/hello\n", string(buf[:n]))
+}
@@ -0,0 +1,46 @@
+// Package kafka publishes the logs of a logger.PublisherWriter to Kafka with
+// github.com/segmentio/kafka-go, so the logger package does not depend on it.
+package kafka
+
+import (
+	"context"
+	"errors"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/trinhdaiphuc/logger"
+)
+
+// Writer writes messages to Kafka, it is implemented by *kafka.Writer of
+// github.com/segmentio/kafka-go.
+type Writer interface {
+	WriteMessages(ctx context.Context, messages ...kafkago.Message) error
+}
+
+// Publisher is a logger.Publisher writing the messages to a Kafka topic. The partition of the
+// messages is chosen by the Balancer of the writer from their key, e.g. kafka.Hash. The
+// messages are acknowledged according to the RequiredAcks of the writer.
+type Publisher struct {
+	// Writer is the Kafka writer, e.g. &kafka.Writer{Addr: kafka.TCP("localhost:9092"), Balancer: &kafka.Hash{}}.
+	Writer Writer
+
+	// Topic is the topic of the messages, empty when it is set by the writer.
+	Topic string
+}
+
+// Publish writes the messages to Kafka.
+func (p *Publisher) Publish(ctx context.Context, messages []logger.Message) error {
+	kafkaMessages := make([]kafkago.Message, len(messages))
+	for i, message := range messages {
+		kafkaMessages[i] = kafkago.Message{
+			Topic: p.Topic,
+			Key:   message.Key,
+			Value: message.Value,
+			Time:  message.Time,
+		}
+	}
+	err := p.Writer.WriteMessages(ctx, kafkaMessages...)
+	var writeErrors kafkago.WriteErrors
+	if errors.As(err, &writeErrors) && len(writeErrors) == len(messages) {
+		return &logger.PublishError{Errors: writeErrors}
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/trinhdaiphuc/logger"
+	"testing"
+	"time"
+)
+
+// writer records the messages written, failing the messages with a key in fail.
+type writer struct {
+	messages []kafkago.Message
+	fail     map[string]error
+}
+
+func (w *writer) WriteMessages(ctx context.Context, messages ...kafkago.Message) error {
+	var (
+		errs   = make(kafkago.WriteErrors, len(messages))
+		failed bool
+	)
+	for i, message := range messages {
+		if errs[i] = w.fail[string(message.Key)]; errs[i] != nil {
+			failed = true
+			continue
+		}
+		w.messages = append(w.messages, message)
+	}
+	if failed {
+		return errs
+	}
+	return nil
+}
+
+func TestPublisher(t *testing.T) {
+	var (
+		now = time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)
+		w   = &writer{fail: map[string]error{"bad": kafkago.NotEnoughReplicas}}
+		p   = &Publisher{Writer: w, Topic: "logs"}
+	)
+	err := p.Publish(context.Background(), []logger.Message{
+		{Key: []byte("hello"), Value: []byte(`{"msg":"a"}`), Time: now},
+		{Key: []byte("bad"), Value: []byte(`{"msg":"b"}`), Time: now},
+	})
+	var publishErr *logger.PublishError
+	if assert.True(t, errors.As(err, &publishErr)) {
+		assert.Equal(t, []error{nil, kafkago.NotEnoughReplicas}, publishErr.Errors)
+	}
+	assert.Equal(t, []kafkago.Message{
+		{Topic: "logs", Key: []byte("hello"), Value: []byte(`{"msg":"a"}`), Time: now},
+	}, w.messages)
+}
+
+func TestPublisherWriter(t *testing.T) {
+	w := &writer{}
+	pw := logger.NewPublisherWriter(&Publisher{Writer: w, Topic: "logs"}, logger.ConfigPublisher{})
+	_, err := pw.Write([]byte(`{"msg":"hello"}` + "\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, pw.Close())
+	if assert.Len(t, w.messages, 1) {
+		assert.Equal(t, "logs", w.messages[0].Topic)
+		assert.Equal(t, `{"msg":"hello"}`+"\n", string(w.messages[0].Value))
+	}
+}
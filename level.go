@@ -0,0 +1,196 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLevelRegistry is the level registry used by the middlewares when none is configured.
+var DefaultLevelRegistry = NewLevelRegistry()
+
+// LevelOverride is a log level set at runtime, for the whole service when Route and Method are
+// empty, for a route (or a gRPC full method), a HTTP method or both otherwise.
+type LevelOverride struct {
+	Level     string     `json:"level"`
+	Method    string     `json:"method,omitempty"`
+	Route     string     `json:"route,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// LevelRegistry holds the log levels of the request logs set at runtime. The global level
+// defaults to the level of the standard logger. It is safe for concurrent use.
+type LevelRegistry struct {
+	sync.RWMutex
+	levels map[levelKey]*levelEntry
+}
+
+type levelKey struct {
+	method string
+	route  string
+}
+
+type levelEntry struct {
+	level   logrus.Level
+	expires time.Time
+	timer   *time.Timer
+	// previous is the level without ttl replaced by a level with a ttl, restored when it expires.
+	previous *levelEntry
+}
+
+// NewLevelRegistry returns an empty level registry.
+func NewLevelRegistry() *LevelRegistry {
+	return &LevelRegistry{levels: make(map[levelKey]*levelEntry)}
+}
+
+// Level returns the level of a request, the first level set for method and route, for route,
+// for method, or globally.
+func (r *LevelRegistry) Level(method, route string) logrus.Level {
+	r.RLock()
+	defer r.RUnlock()
+	for _, key := range []levelKey{{method, route}, {"", route}, {method, ""}, {}} {
+		if entry, ok := r.levels[key]; ok {
+			return entry.level
+		}
+	}
+	return logrus.StandardLogger().GetLevel()
+}
+
+// Set sets the level of method and route, the global level when both are empty. The level is
+// reverted after ttl when it is positive, to the last level set without ttl if any.
+func (r *LevelRegistry) Set(method, route string, level logrus.Level, ttl time.Duration) {
+	key := levelKey{method: method, route: route}
+	entry := &levelEntry{level: level}
+
+	r.Lock()
+	defer r.Unlock()
+	previous, ok := r.levels[key]
+	if ok && previous.timer != nil {
+		previous.timer.Stop()
+		previous = previous.previous
+	}
+	if ttl > 0 {
+		entry.previous = previous
+		entry.expires = time.Now().Add(ttl)
+		entry.timer = time.AfterFunc(ttl, func() {
+			r.Lock()
+			defer r.Unlock()
+			if r.levels[key] != entry {
+				return
+			}
+			if entry.previous != nil {
+				r.levels[key] = entry.previous
+			} else {
+				delete(r.levels, key)
+			}
+		})
+	}
+	r.levels[key] = entry
+}
+
+// Delete reverts the level of method and route, the global level when both are empty, including
+// the level restored when a level with a ttl expires.
+func (r *LevelRegistry) Delete(method, route string) {
+	key := levelKey{method: method, route: route}
+
+	r.Lock()
+	defer r.Unlock()
+	if entry, ok := r.levels[key]; ok {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+		delete(r.levels, key)
+	}
+}
+
+// Overrides returns the levels set in the registry, the global level first.
+func (r *LevelRegistry) Overrides() []LevelOverride {
+	r.RLock()
+	defer r.RUnlock()
+	overrides := make([]LevelOverride, 0, len(r.levels))
+	for key, entry := range r.levels {
+		override := LevelOverride{Level: entry.level.String(), Method: key.method, Route: key.route}
+		if !entry.expires.IsZero() {
+			expires := entry.expires
+			override.ExpiresAt = &expires
+		}
+		overrides = append(overrides, override)
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		if overrides[i].Route != overrides[j].Route {
+			return overrides[i].Route < overrides[j].Route
+		}
+		return overrides[i].Method < overrides[j].Method
+	})
+	return overrides
+}
+
+// Apply sets or, when Level is empty, deletes a level override.
+func (r *LevelRegistry) Apply(override LevelOverride) error {
+	if override.Level == "" {
+		r.Delete(override.Method, override.Route)
+		return nil
+	}
+	level, err := logrus.ParseLevel(override.Level)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if override.TTL != "" {
+		if ttl, err = time.ParseDuration(override.TTL); err != nil {
+			return fmt.Errorf("invalid ttl %q: %w", override.TTL, err)
+		}
+	}
+	r.Set(override.Method, override.Route, level, ttl)
+	return nil
+}
+
+// levelsResponse is the response of the level admin endpoints.
+type levelsResponse struct {
+	Global    string          `json:"global"`
+	Overrides []LevelOverride `json:"overrides"`
+}
+
+func (r *LevelRegistry) response() levelsResponse {
+	return levelsResponse{
+		Global:    r.Level("", "").String(),
+		Overrides: r.Overrides(),
+	}
+}
+
+// ServeHTTP is the admin handler of the registry:
+//   - GET returns the global level and the overrides.
+//   - PUT sets the level override of the json body, e.g. {"level":"debug","route":"/users/:id","ttl":"10m"}.
+//   - DELETE deletes the override of the method and route query parameters.
+func (r *LevelRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var override LevelOverride
+		if err := json.NewDecoder(req.Body).Decode(&override); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if override.Level == "" {
+			http.Error(w, "empty level", http.StatusBadRequest)
+			return
+		}
+		if err := r.Apply(override); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		r.Delete(req.URL.Query().Get("method"), req.URL.Query().Get("route"))
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(r.response())
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// LevelAdminServiceName is the name of the gRPC level admin service.
+const LevelAdminServiceName = "logger.LevelAdmin"
+
+// LevelAdminServer is the gRPC level admin service. The messages are google.protobuf.Struct with
+// the json fields of the HTTP admin handler of LevelRegistry.
+type LevelAdminServer interface {
+	// GetLevels returns the global level and the overrides.
+	GetLevels(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	// SetLevel sets a level override, e.g. {"level":"debug","route":"/hello.HelloService/Hello","ttl":"10m"}.
+	SetLevel(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	// DeleteLevel deletes the level override of the method and route of the request.
+	DeleteLevel(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+// RegisterLevelAdmin registers the level admin service of registry to s.
+func RegisterLevelAdmin(s grpc.ServiceRegistrar, registry *LevelRegistry) {
+	s.RegisterService(&levelAdminServiceDesc, &levelAdminServer{registry: registry})
+}
+
+type levelAdminServer struct {
+	registry *LevelRegistry
+}
+
+func (s *levelAdminServer) GetLevels(context.Context, *emptypb.Empty) (*structpb.Struct, error) {
+	return levelsStruct(s.registry.response())
+}
+
+func (s *levelAdminServer) SetLevel(_ context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	override, err := levelOverride(req)
+	if err != nil {
+		return nil, err
+	}
+	if override.Level == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty level")
+	}
+	if err := s.registry.Apply(override); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return levelsStruct(s.registry.response())
+}
+
+func (s *levelAdminServer) DeleteLevel(_ context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	override, err := levelOverride(req)
+	if err != nil {
+		return nil, err
+	}
+	s.registry.Delete(override.Method, override.Route)
+	return levelsStruct(s.registry.response())
+}
+
+func levelOverride(req *structpb.Struct) (LevelOverride, error) {
+	var override LevelOverride
+	data, err := protojson.Marshal(req)
+	if err == nil {
+		err = json.Unmarshal(data, &override)
+	}
+	if err != nil {
+		return override, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return override, nil
+}
+
+func levelsStruct(response levelsResponse) (*structpb.Struct, error) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	levels := &structpb.Struct{}
+	if err := protojson.Unmarshal(data, levels); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return levels, nil
+}
+
+// LevelAdminClient is the client of the gRPC level admin service.
+type LevelAdminClient interface {
+	GetLevels(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*structpb.Struct, error)
+	SetLevel(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	DeleteLevel(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+}
+
+// NewLevelAdminClient returns a client of the level admin service served on cc.
+func NewLevelAdminClient(cc grpc.ClientConnInterface) LevelAdminClient {
+	return &levelAdminClient{cc: cc}
+}
+
+type levelAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func (c *levelAdminClient) GetLevels(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, "/"+LevelAdminServiceName+"/GetLevels", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelAdminClient) SetLevel(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, "/"+LevelAdminServiceName+"/SetLevel", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelAdminClient) DeleteLevel(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, "/"+LevelAdminServiceName+"/DeleteLevel", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// levelAdminServiceDesc is written by hand, as the service has no proto file: its messages are the
+// untyped well-known google.protobuf.Struct and google.protobuf.Empty, so it has no Metadata and is
+// not listed with its descriptor by the reflection service.
+var levelAdminServiceDesc = grpc.ServiceDesc{
+	ServiceName: LevelAdminServiceName,
+	HandlerType: (*LevelAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLevels",
+			Handler:    levelAdminHandler("GetLevels", LevelAdminServer.GetLevels),
+		},
+		{
+			MethodName: "SetLevel",
+			Handler:    levelAdminHandler("SetLevel", LevelAdminServer.SetLevel),
+		},
+		{
+			MethodName: "DeleteLevel",
+			Handler:    levelAdminHandler("DeleteLevel", LevelAdminServer.DeleteLevel),
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// levelAdminHandler returns the handler of method decoding its request into a new Req.
+func levelAdminHandler[Req any](
+	method string,
+	call func(LevelAdminServer, context.Context, *Req) (*structpb.Struct, error),
+) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(Req)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(LevelAdminServer), ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + LevelAdminServiceName + "/" + method}
+		return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(LevelAdminServer), ctx, req.(*Req))
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	pb "github.com/trinhdaiphuc/logger/proto/hello"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLevelRegistry(t *testing.T) {
+	registry := NewLevelRegistry()
+	assert.Equal(t, logrus.StandardLogger().GetLevel(), registry.Level("GET", "/users/:id"))
+
+	registry.Set("", "", logrus.WarnLevel, 0)
+	registry.Set("", "/users/:id", logrus.DebugLevel, 0)
+	registry.Set("DELETE", "/users/:id", logrus.TraceLevel, 0)
+
+	assert.Equal(t, logrus.WarnLevel, registry.Level("GET", "/hello"))
+	assert.Equal(t, logrus.DebugLevel, registry.Level("GET", "/users/:id"))
+	assert.Equal(t, logrus.TraceLevel, registry.Level("DELETE", "/users/:id"))
+	assert.Equal(t, []LevelOverride{
+		{Level: "warning"},
+		{Level: "debug", Route: "/users/:id"},
+		{Level: "trace", Method: "DELETE", Route: "/users/:id"},
+	}, registry.Overrides())
+
+	registry.Delete("DELETE", "/users/:id")
+	assert.Equal(t, logrus.DebugLevel, registry.Level("DELETE", "/users/:id"))
+
+	assert.Nil(t, registry.Apply(LevelOverride{Route: "/users/:id"}))
+	assert.Equal(t, logrus.WarnLevel, registry.Level("GET", "/users/:id"))
+	assert.Nil(t, registry.Apply(LevelOverride{Level: "info", Method: "GET"}))
+	assert.Equal(t, logrus.InfoLevel, registry.Level("GET", "/users/:id"))
+	assert.Equal(t, logrus.WarnLevel, registry.Level("POST", "/users/:id"))
+	assert.NotNil(t, registry.Apply(LevelOverride{Level: "verbose"}))
+	assert.NotNil(t, registry.Apply(LevelOverride{Level: "debug", TTL: "soon"}))
+}
+
+func TestLevelRegistryTTL(t *testing.T) {
+	registry := NewLevelRegistry()
+	registry.Set("", "/hello", logrus.DebugLevel, 20*time.Millisecond)
+	assert.Equal(t, logrus.DebugLevel, registry.Level("GET", "/hello"))
+	assert.NotNil(t, registry.Overrides()[0].ExpiresAt)
+
+	// A new level of the same route is not reverted by the timer of the previous one.
+	registry.Set("", "/users", logrus.DebugLevel, 20*time.Millisecond)
+	registry.Set("", "/users", logrus.TraceLevel, 0)
+
+	// The level set without ttl is restored when the level with a ttl expires, also when a
+	// level with a ttl replaces another one.
+	registry.Set("", "", logrus.WarnLevel, 0)
+	registry.Set("", "", logrus.DebugLevel, time.Hour)
+	registry.Set("", "", logrus.TraceLevel, 20*time.Millisecond)
+	assert.Equal(t, logrus.TraceLevel, registry.Level("GET", "/orders"))
+
+	assert.Eventually(t, func() bool {
+		return registry.Level("GET", "/hello") == logrus.WarnLevel
+	}, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return registry.Level("GET", "/orders") == logrus.WarnLevel
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, logrus.TraceLevel, registry.Level("GET", "/users"))
+	assert.Nil(t, registry.Overrides()[0].ExpiresAt)
+
+	registry.Set("", "", logrus.DebugLevel, time.Hour)
+	registry.Delete("", "")
+	assert.Equal(t, logrus.StandardLogger().GetLevel(), registry.Level("GET", "/orders"))
+}
+
+func TestLevelRegistryConcurrency(t *testing.T) {
+	var (
+		registry = NewLevelRegistry()
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				registry.Set("", "/hello", logrus.DebugLevel, time.Millisecond)
+				registry.Delete("", "/hello")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				registry.Level("GET", "/hello")
+				registry.Overrides()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLevelRegistryHTTP(t *testing.T) {
+	registry := NewLevelRegistry()
+
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest("PUT", "/levels", strings.NewReader(`{"level":"debug","route":"/hello","ttl":"1m"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response levelsResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Overrides, 1)
+	assert.Equal(t, "debug", response.Overrides[0].Level)
+	assert.Equal(t, logrus.DebugLevel, registry.Level("GET", "/hello"))
+
+	w = httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest("PUT", "/levels", strings.NewReader(`{"level":"trace","method":"POST"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, logrus.TraceLevel, registry.Level("POST", "/users"))
+	registry.Delete("POST", "")
+
+	w = httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest("PUT", "/levels", strings.NewReader(`{"route":"/hello"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest("DELETE", "/levels?route=/hello", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, registry.Overrides())
+
+	w = httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest("PATCH", "/levels", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestLevelAdminGrpc(t *testing.T) {
+	var (
+		registry = NewLevelRegistry()
+		listener = bufconn.Listen(1024 * 1024)
+		server   = grpc.NewServer()
+	)
+	RegisterLevelAdmin(server, registry)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), "", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	client := NewLevelAdminClient(conn)
+
+	req, _ := structpb.NewStruct(map[string]interface{}{"level": "debug", "route": "/hello.HelloService/Hello"})
+	levels, err := client.SetLevel(context.Background(), req)
+	assert.Nil(t, err)
+	assert.Len(t, levels.Fields["overrides"].GetListValue().GetValues(), 1)
+	assert.Equal(t, logrus.DebugLevel, registry.Level("", "/hello.HelloService/Hello"))
+
+	req, _ = structpb.NewStruct(map[string]interface{}{"level": "verbose"})
+	_, err = client.SetLevel(context.Background(), req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	req, _ = structpb.NewStruct(map[string]interface{}{"route": "/hello.HelloService/Hello"})
+	_, err = client.DeleteLevel(context.Background(), req)
+	assert.Nil(t, err)
+	levels, err = client.GetLevels(context.Background(), &emptypb.Empty{})
+	assert.Nil(t, err)
+	assert.Equal(t, logrus.StandardLogger().GetLevel().String(), levels.Fields["global"].GetStringValue())
+	assert.Empty(t, levels.Fields["overrides"].GetListValue().GetValues())
+}
+
+func TestLevelMiddlewares(t *testing.T) {
+	registry := NewLevelRegistry()
+	registry.Set("", "/users/:id", logrus.DebugLevel, 0)
+	registry.Set("", "/quiet", logrus.WarnLevel, 0)
+	registry.Set("", "/hello.HelloService/Hello", logrus.DebugLevel, 0)
+
+	assertDebugLog := func(t *testing.T, buf *bytes.Buffer, step string) {
+		data := decodeLog(t, buf)
+		assert.Equal(t, "debug step", data[step])
+	}
+
+	t.Run("Gin", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := gin.New()
+		server.Use(GinMiddleware(ConfigGin{Levels: registry}))
+		server.GET("/users/:id", func(ctx *gin.Context) {
+			GetLogger(ctx).AddDebugLog("debug step")
+		})
+		server.GET("/quiet", func(ctx *gin.Context) {})
+		performRequest(server, "GET", "/users/u1")
+		assertDebugLog(t, buf, "STEP_1")
+
+		buf.Reset()
+		performRequest(server, "GET", "/quiet")
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("Echo", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := echo.New()
+		server.Use(EchoMiddleware(ConfigEcho{Levels: registry}))
+		server.GET("/users/:id", func(ctx echo.Context) error {
+			GetLogger(ctx.Request().Context()).AddDebugLog("debug step")
+			return nil
+		})
+		performRequest(server, "GET", "/users/u1")
+		assertDebugLog(t, buf, "STEP_1")
+	})
+
+	t.Run("Fiber", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := fiber.New()
+		server.Use(FiberMiddleware(ConfigFiber{Levels: registry}))
+		server.Get("/quiet", func(ctx *fiber.Ctx) error {
+			return nil
+		})
+		_, err := server.Test(httptest.NewRequest("GET", "/quiet", nil))
+		assert.Nil(t, err)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("Grpc", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		conn, err := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(dialer(ConfigGrpc{Levels: registry})))
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		_, err = pb.NewHelloServiceClient(conn).Hello(context.Background(), &pb.HelloRequest{Name: "debug"})
+		assert.Nil(t, err)
+		assertDebugLog(t, buf, "STEP_2")
+	})
+}
+
+func TestLevelMiddlewareConcurrentOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	defer New(WithOutput(os.Stderr))
+	registry := NewLevelRegistry()
+	registry.Set("", "/users/:id", logrus.DebugLevel, 0)
+	server := gin.New()
+	server.Use(GinMiddleware(ConfigGin{Levels: registry}))
+	server.GET("/users/:id", func(ctx *gin.Context) {
+		GetLogger(ctx).AddDebugLog("debug step")
+	})
+	server.GET("/hello", func(ctx *gin.Context) {})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for _, path := range []string{"/users/u1", "/hello"} {
+			wg.Add(1)
+			go func(path string) {
+				defer wg.Done()
+				performRequest(server, "GET", path)
+			}(path)
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, 100, strings.Count(buf.String(), "\n"))
+	assert.Equal(t, 50, strings.Count(buf.String(), `"STEP_1":"debug step"`))
+}
+
+func TestLevelMiddlewaresStandardLevel(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	defer logrus.SetLevel(logrus.InfoLevel)
+	registry := NewLevelRegistry()
+	registry.Set("", "/users/:id", logrus.DebugLevel, 0)
+	registry.Set(http.MethodPost, "", logrus.InfoLevel, 0)
+
+	t.Run("Gin", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := gin.New()
+		server.Use(GinMiddleware(ConfigGin{Levels: registry}))
+		server.GET("/users/:id", func(ctx *gin.Context) {
+			GetLogger(ctx).AddDebugLog("debug step")
+		})
+		server.GET("/hello", func(ctx *gin.Context) {})
+		server.POST("/hello", func(ctx *gin.Context) {})
+		performRequest(server, "GET", "/users/u1")
+		data := decodeLog(t, buf)
+		assert.Equal(t, "debug step", data["STEP_1"])
+
+		buf.Reset()
+		performRequest(server, "POST", "/hello")
+		data = decodeLog(t, buf)
+		assert.Equal(t, "info", data["level"])
+
+		buf.Reset()
+		performRequest(server, "GET", "/hello")
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("Echo", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := echo.New()
+		server.Use(EchoMiddleware(ConfigEcho{Levels: registry}))
+		server.GET("/users/:id", func(ctx echo.Context) error {
+			GetLogger(ctx.Request().Context()).AddDebugLog("debug step")
+			return nil
+		})
+		performRequest(server, "GET", "/users/u1")
+		data := decodeLog(t, buf)
+		assert.Equal(t, "debug step", data["STEP_1"])
+	})
+
+	t.Run("Grpc", func(t *testing.T) {
+		registry.Set("", "/hello.HelloService/Hello", logrus.DebugLevel, 0)
+		defer registry.Delete("", "/hello.HelloService/Hello")
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		conn, err := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(dialer(ConfigGrpc{Levels: registry})))
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		_, err = pb.NewHelloServiceClient(conn).Hello(context.Background(), &pb.HelloRequest{Name: "debug"})
+		assert.Nil(t, err)
+		data := decodeLog(t, buf)
+		assert.Equal(t, "debug step", data["STEP_2"])
+	})
+}
+
+func TestLevelFiber(t *testing.T) {
+	logrus.SetLevel(logrus.WarnLevel)
+	defer logrus.SetLevel(logrus.InfoLevel)
+	registry := NewLevelRegistry()
+	registry.Set("", "/users/:id", logrus.DebugLevel, 0)
+
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	server := fiber.New()
+	server.Use(FiberMiddleware(ConfigFiber{Levels: registry}))
+	server.All("/users/:id", func(ctx *fiber.Ctx) error {
+		GetLogger(ctx.Context()).AddDebugLog("debug step")
+		return nil
+	})
+
+	server.Get("/hello", func(ctx *fiber.Ctx) error {
+		GetLogger(ctx.Context()).AddDebugLog("debug step")
+		return nil
+	})
+
+	// The level of the route applies to the steps of the handler.
+	_, err := server.Test(httptest.NewRequest("GET", "/users/42", nil))
+	assert.Nil(t, err)
+	data := decodeLog(t, buf)
+	assert.Equal(t, "/users/:id", data[RouteField])
+	assert.Equal(t, "debug step", data["STEP_1"])
+
+	// The other routes are not logged.
+	buf.Reset()
+	_, err = server.Test(httptest.NewRequest("GET", "/hello", nil))
+	assert.Nil(t, err)
+	assert.Empty(t, buf.String())
+
+	// The level of the method applies to the handler.
+	registry.Set(http.MethodPost, "", logrus.DebugLevel, 0)
+	registry.Delete("", "/users/:id")
+	buf.Reset()
+	_, err = server.Test(httptest.NewRequest("POST", "/users/42", nil))
+	assert.Nil(t, err)
+	data = decodeLog(t, buf)
+	assert.Equal(t, "debug step", data["STEP_1"])
+}
@@ -12,7 +12,13 @@ import (
 type Log struct {
 	*logrus.Entry
 	sync.Mutex
-	step int32
+	step  int32
+	level logrus.Level
+	debug *debugRequest
+
+	// levelFunc resolves the level when it is read, set by the middlewares which only know the
+	// route of the request once its handler is called.
+	levelFunc func() logrus.Level
 }
 
 type TextFormatter = logrus.TextFormatter
@@ -26,6 +32,9 @@ const (
 	RequestMethodField = "request_method"
 	UserAgentField     = "user_agent"
 	URIField           = "uri"
+	RouteField         = "route"
+	PathField          = "path"
+	QueryField         = "query"
 	StatusField        = "Status"
 	ErrorsField        = "Errors"
 	EndField           = "end"
@@ -33,6 +42,11 @@ const (
 	RequestField       = "request"
 	ResponseField      = "response"
 	StartField         = "start"
+	GrpcServiceField   = "grpc_service"
+	GrpcMethodField    = "grpc_method"
+	AuthorityField     = "authority"
+	DeadlineField      = "deadline"
+	ContextErrorField  = "context_error"
 )
 
 // New return a new log object with log start time
@@ -43,18 +57,49 @@ func New(opts ...Option) *Log {
 
 	return &Log{
 		Entry: logrus.NewEntry(logrus.StandardLogger()),
+		level: logrus.StandardLogger().GetLevel(),
 	}
 }
 
+// standard is the configuration of the standard logger set by the options. The middlewares set
+// the formatter for every request, so the request loggers read it here under the lock rather
+// than from the standard logger.
+var standard struct {
+	sync.Mutex
+	formatter Formatter
+	out       *lockedWriter
+	sinks     bool
+
+	// unsunk are the output and the formatter replaced by WithSinks, restored when the sinks
+	// are removed.
+	unsunkOut       io.Writer
+	unsunkFormatter Formatter
+}
+
 func WithFormatter(formatter Formatter) Option {
 	return func() {
+		standard.Lock()
+		defer standard.Unlock()
+		// The logs written to sinks are formatted by the sinks.
+		if standard.sinks {
+			return
+		}
+		standard.formatter = formatter
 		logrus.SetFormatter(formatter)
 	}
 }
 
 func WithOutput(output io.Writer) Option {
 	return func() {
-		logrus.SetOutput(output)
+		standard.Lock()
+		defer standard.Unlock()
+		standard.out = &lockedWriter{Writer: output}
+		// The logs written to sinks are written by the sinks, output is used once they are removed.
+		if standard.sinks {
+			standard.unsunkOut = standard.out
+			return
+		}
+		logrus.SetOutput(standard.out)
 	}
 }
 
@@ -107,6 +152,106 @@ func (l *Log) AddLog(line string, format ...interface{}) *Log {
 	return l
 }
 
+// AddDebugLog add a new field to log like AddLog when the level of the log is debug or trace
+func (l *Log) AddDebugLog(line string, format ...interface{}) *Log {
+	if !l.IsLevelEnabled(logrus.DebugLevel) {
+		return l
+	}
+	return l.AddLog(line, format...)
+}
+
+// SetLevel set the level of the request log, set by the middlewares from their level registry
+func (l *Log) SetLevel(level logrus.Level) *Log {
+	l.Lock()
+	defer l.Unlock()
+	l.levelFunc = nil
+	l.setLevel(level)
+	return l
+}
+
+// setLevelFunc resolves the level of the request log with levelFunc until the next SetLevel.
+func (l *Log) setLevelFunc(levelFunc func() logrus.Level) {
+	l.Lock()
+	defer l.Unlock()
+	l.levelFunc = levelFunc
+}
+
+// setLevel is called with the lock of l.
+func (l *Log) setLevel(level logrus.Level) {
+	l.level = level
+	if logger := requestLogger(level); logger != l.Entry.Logger {
+		l.Entry = l.Entry.Dup()
+		l.Entry.Logger = logger
+	}
+}
+
+// requestLogger returns the logger of a request log at level. logrus drops the entries less
+// severe than the level of their logger, so a request log more verbose than the standard logger
+// is written by a copy of the standard logger at its own level, sharing its output, formatter
+// and hooks. The output is shared through a lockedWriter, as the copies don't share the lock of
+// the standard logger.
+func requestLogger(level logrus.Level) *logrus.Logger {
+	std := logrus.StandardLogger()
+	if level <= std.GetLevel() {
+		return std
+	}
+	standard.Lock()
+	defer standard.Unlock()
+	formatter := standard.formatter
+	if formatter == nil || standard.sinks {
+		formatter = std.Formatter
+	}
+	return &logrus.Logger{
+		Out:          lockedOutput(std),
+		Hooks:        std.Hooks,
+		Formatter:    formatter,
+		ReportCaller: std.ReportCaller,
+		Level:        level,
+		ExitFunc:     std.ExitFunc,
+		BufferPool:   std.BufferPool,
+	}
+}
+
+// lockedWriter serializes the writes of the standard logger and of its copies to their output.
+type lockedWriter struct {
+	mu sync.Mutex
+	io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.Writer.Write(p)
+}
+
+// lockedOutput returns the output of the standard logger wrapped in a lockedWriter, wrapping it
+// when it was not set by WithOutput. It is called with the lock of standard.
+func lockedOutput(std *logrus.Logger) io.Writer {
+	if standard.out != nil && std.Out == io.Writer(standard.out) {
+		return standard.out
+	}
+	standard.out = &lockedWriter{Writer: std.Out}
+	std.SetOutput(standard.out)
+	return standard.out
+}
+
+// GetLevel get the level of the request log
+func (l *Log) GetLevel() logrus.Level {
+	l.Lock()
+	defer l.Unlock()
+	if l.levelFunc != nil {
+		if level := l.levelFunc(); level != l.level {
+			l.setLevel(level)
+		}
+	}
+	return l.level
+}
+
+// IsLevelEnabled checks if the level of the request log is greater than level
+func (l *Log) IsLevelEnabled(level logrus.Level) bool {
+	return l.GetLevel() >= level
+}
+
 // WithField add a new key = value to log with key = field, value = value
 func (l *Log) WithField(field string, value interface{}) *Log {
 	l.Entry = l.Entry.WithField(field, value)
@@ -0,0 +1,337 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultLogMetricsBuckets are the buckets of the format and write latencies, in seconds.
+var DefaultLogMetricsBuckets = []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1}
+
+// ConfigLogMetrics defines the metrics of the logs.
+type ConfigLogMetrics struct {
+	// RouteField is the field of the route of the request lines. Default is RouteField.
+	RouteField string
+
+	// ErrorTypesField is the field of the error types of the request lines. Default is
+	// ErrorTypesField.
+	ErrorTypesField string
+
+	// MaxLabels is the number of routes and of error types counted, the others are counted as
+	// OtherRoute. UnmatchedRoute is not counted in MaxLabels. Default is DefaultMaxRoutes.
+	MaxLabels int
+
+	// Buckets are the buckets of the format and write latencies, in seconds. Default is
+	// DefaultLogMetricsBuckets.
+	Buckets []float64
+}
+
+// DropCounter is a writer dropping or sampling lines, e.g. AsyncWriter, Shipper and
+// PublisherWriter.
+type DropCounter interface {
+	DropCounts() (dropped, sampled uint64)
+}
+
+// LatencyStats are the number and the total duration of the formats or writes of the lines.
+type LatencyStats struct {
+	Count uint64        `json:"count"`
+	Sum   time.Duration `json:"sum"`
+	// Buckets are the cumulative counts by the upper bound of the buckets, in seconds.
+	Buckets map[float64]uint64 `json:"-"`
+}
+
+// LogMetricsStats are the counters of LogMetrics.
+type LogMetricsStats struct {
+	// Levels is the number of lines by level.
+	Levels map[string]uint64 `json:"levels"`
+	// Routes is the number of request lines by route.
+	Routes map[string]uint64 `json:"routes"`
+	// ErrorTypes is the number of request lines by the type of the root cause of their error.
+	ErrorTypes map[string]uint64 `json:"error_types"`
+	// LogrusErrors is the number of lines formatted with invalid fields, logged under logrus_error.
+	LogrusErrors uint64 `json:"logrus_errors"`
+	// FormatErrors is the number of lines which failed to be formatted.
+	FormatErrors uint64 `json:"format_errors"`
+	// Format is the latency of the formats.
+	Format LatencyStats `json:"format"`
+	// WriteErrors is the number of lines which failed to be written, by writer.
+	WriteErrors map[string]uint64 `json:"write_errors"`
+	// Writes is the latency of the writes, by writer.
+	Writes map[string]LatencyStats `json:"writes"`
+	// Dropped and Sampled are the number of lines dropped and sampled, by writer.
+	Dropped map[string]uint64 `json:"dropped"`
+	Sampled map[string]uint64 `json:"sampled"`
+}
+
+// LogMetrics counts the lines logged by level, route and error type, and measures the latency of
+// their formatter and writers. Add it to the standard logger with WithLogMetrics, wrap the
+// formatters and the outputs with Formatter and Writer, and Watch the writers dropping lines.
+// It is an expvar.Var, the NewLogMetrics of the github.com/trinhdaiphuc/logger/prometheus
+// package exports it to Prometheus:
+//
+//	expvar.Publish("logger", metrics)
+type LogMetrics struct {
+	config     ConfigLogMetrics
+	routes     *LabelLimiter
+	errorTypes *LabelLimiter
+
+	mu           sync.Mutex
+	levels       map[string]uint64
+	routeLines   map[string]uint64
+	errorLines   map[string]uint64
+	logrusErrors uint64
+	formatErrors uint64
+	format       *latencyHistogram
+	writes       map[string]*writeMetrics
+	watched      map[string]DropCounter
+}
+
+// writeMetrics are the metrics of a writer.
+type writeMetrics struct {
+	latency *latencyHistogram
+	errors  uint64
+}
+
+// NewLogMetrics returns the metrics defined by config.
+func NewLogMetrics(config ConfigLogMetrics) *LogMetrics {
+	if config.RouteField == "" {
+		config.RouteField = RouteField
+	}
+	if config.ErrorTypesField == "" {
+		config.ErrorTypesField = ErrorTypesField
+	}
+	if config.MaxLabels <= 0 {
+		config.MaxLabels = DefaultMaxRoutes
+	}
+	if config.Buckets == nil {
+		config.Buckets = DefaultLogMetricsBuckets
+	}
+	return &LogMetrics{
+		config:     config,
+		routes:     NewLabelLimiter(config.MaxLabels),
+		errorTypes: NewLabelLimiter(config.MaxLabels),
+		levels:     make(map[string]uint64),
+		routeLines: make(map[string]uint64),
+		errorLines: make(map[string]uint64),
+		format:     newLatencyHistogram(config.Buckets),
+		writes:     make(map[string]*writeMetrics),
+		watched:    make(map[string]DropCounter),
+	}
+}
+
+// WithLogMetrics counts the lines of the standard logger in metrics, replacing the metrics of a
+// previous WithLogMetrics. WithLogMetrics(nil) removes the metrics.
+func WithLogMetrics(metrics *LogMetrics) Option {
+	return func() {
+		standard.Lock()
+		defer standard.Unlock()
+		logger := logrus.StandardLogger()
+		hooks := make(logrus.LevelHooks)
+		for level, levelHooks := range logger.Hooks {
+			for _, hook := range levelHooks {
+				if _, ok := hook.(*LogMetrics); !ok {
+					hooks[level] = append(hooks[level], hook)
+				}
+			}
+		}
+		if metrics != nil {
+			hooks.Add(metrics)
+		}
+		logger.ReplaceHooks(hooks)
+	}
+}
+
+// Levels implements logrus.Hook.
+func (m *LogMetrics) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook, it counts entry.
+func (m *LogMetrics) Fire(entry *logrus.Entry) error {
+	var route, errorType string
+	if value, ok := entry.Data[m.config.RouteField]; ok {
+		route = m.routes.Label(fieldString(value))
+	}
+	switch types := entry.Data[m.config.ErrorTypesField].(type) {
+	case []string:
+		if len(types) > 0 {
+			errorType = m.errorTypes.Label(types[len(types)-1])
+		}
+	case string:
+		errorType = m.errorTypes.Label(types)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.levels[entry.Level.String()]++
+	if route != "" {
+		m.routeLines[route]++
+	}
+	if errorType != "" {
+		m.errorLines[errorType]++
+	}
+	return nil
+}
+
+// Formatter returns formatter measuring the latency and the errors of its formats.
+func (m *LogMetrics) Formatter(formatter Formatter) Formatter {
+	return &metricsFormatter{Formatter: formatter, metrics: m}
+}
+
+// Writer returns w measuring the latency and the errors of its writes, under name.
+func (m *LogMetrics) Writer(name string, w io.Writer) io.Writer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	metrics, ok := m.writes[name]
+	if !ok {
+		metrics = &writeMetrics{latency: newLatencyHistogram(m.config.Buckets)}
+		m.writes[name] = metrics
+	}
+	return &metricsWriter{Writer: w, metrics: m, write: metrics}
+}
+
+// Watch reports the lines dropped and sampled by w under name.
+func (m *LogMetrics) Watch(name string, w DropCounter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watched[name] = w
+}
+
+// Stats returns the counters of the metrics.
+func (m *LogMetrics) Stats() LogMetricsStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := LogMetricsStats{
+		Levels:       copyCounts(m.levels),
+		Routes:       copyCounts(m.routeLines),
+		ErrorTypes:   copyCounts(m.errorLines),
+		LogrusErrors: m.logrusErrors,
+		FormatErrors: m.formatErrors,
+		Format:       m.format.stats(),
+		WriteErrors:  make(map[string]uint64, len(m.writes)),
+		Writes:       make(map[string]LatencyStats, len(m.writes)),
+		Dropped:      make(map[string]uint64, len(m.watched)),
+		Sampled:      make(map[string]uint64, len(m.watched)),
+	}
+	for name, write := range m.writes {
+		stats.WriteErrors[name] = write.errors
+		stats.Writes[name] = write.latency.stats()
+	}
+	for name, w := range m.watched {
+		stats.Dropped[name], stats.Sampled[name] = w.DropCounts()
+	}
+	return stats
+}
+
+// String implements expvar.Var, it returns the Stats as JSON.
+func (m *LogMetrics) String() string {
+	b, _ := json.Marshal(m.Stats())
+	return string(b)
+}
+
+// metricsFormatter is a Formatter measured by LogMetrics.
+type metricsFormatter struct {
+	Formatter
+	metrics *LogMetrics
+}
+
+func (f *metricsFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	start := time.Now()
+	line, err := f.Formatter.Format(entry)
+	latency := time.Since(start)
+
+	f.metrics.mu.Lock()
+	defer f.metrics.mu.Unlock()
+	f.metrics.format.observe(latency)
+	if err != nil {
+		f.metrics.formatErrors++
+	}
+	// logrus drops the invalid fields of an entry and reports them under logrus_error when the
+	// entry is formatted.
+	if hasLogrusError(line) {
+		f.metrics.logrusErrors++
+	}
+	return line, err
+}
+
+var (
+	jsonLogrusError   = []byte(`"` + logrus.FieldKeyLogrusError + `":`)
+	logfmtLogrusError = []byte(logrus.FieldKeyLogrusError + "=")
+)
+
+// hasLogrusError reports whether line has the logrus_error key, written as a JSON key or a
+// logfmt key. The lines of the other formatters are not counted.
+func hasLogrusError(line []byte) bool {
+	if bytes.Contains(line, jsonLogrusError) || bytes.HasPrefix(line, logfmtLogrusError) {
+		return true
+	}
+	return bytes.Contains(line, append([]byte{' '}, logfmtLogrusError...))
+}
+
+// metricsWriter is a writer measured by LogMetrics.
+type metricsWriter struct {
+	io.Writer
+	metrics *LogMetrics
+	write   *writeMetrics
+}
+
+func (w *metricsWriter) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := w.Writer.Write(p)
+	latency := time.Since(start)
+
+	w.metrics.mu.Lock()
+	defer w.metrics.mu.Unlock()
+	w.write.latency.observe(latency)
+	if err != nil {
+		w.write.errors++
+	}
+	return n, err
+}
+
+// latencyHistogram is a histogram of latencies, in seconds.
+type latencyHistogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     time.Duration
+}
+
+func newLatencyHistogram(buckets []float64) *latencyHistogram {
+	return &latencyHistogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *latencyHistogram) observe(latency time.Duration) {
+	h.count++
+	h.sum += latency
+	for i, bucket := range h.buckets {
+		if latency.Seconds() <= bucket {
+			h.counts[i]++
+			break
+		}
+	}
+}
+
+func (h *latencyHistogram) stats() LatencyStats {
+	var (
+		buckets    = make(map[float64]uint64, len(h.buckets))
+		cumulative uint64
+	)
+	for i, bucket := range h.buckets {
+		cumulative += h.counts[i]
+		buckets[bucket] = cumulative
+	}
+	return LatencyStats{Count: h.count, Sum: h.sum, Buckets: buckets}
+}
+
+func copyCounts(counts map[string]uint64) map[string]uint64 {
+	c := make(map[string]uint64, len(counts))
+	for k, v := range counts {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+type dropCounter struct {
+	dropped, sampled uint64
+}
+
+func (c dropCounter) DropCounts() (dropped, sampled uint64) {
+	return c.dropped, c.sampled
+}
+
+type failingFormatter struct{}
+
+func (failingFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, errors.New("format failed")
+}
+
+func TestLogMetrics(t *testing.T) {
+	var (
+		buf     = &bytes.Buffer{}
+		metrics = NewLogMetrics(ConfigLogMetrics{MaxLabels: 2})
+	)
+	New(WithLogMetrics(metrics), WithOutput(metrics.Writer("buffer", buf)), WithFormatter(metrics.Formatter(&JSONFormatter{})))
+	defer New(WithLogMetrics(nil), WithOutput(os.Stderr))
+	metrics.Watch("async", dropCounter{dropped: 3, sampled: 2})
+
+	for _, route := range []string{"/users/:id", "/users/:id", "/orders", "/items"} {
+		New().WithField(RouteField, route).Info("latency: 1ms")
+	}
+	New().WithFields(map[string]interface{}{
+		RouteField:      "/orders",
+		ErrorTypesField: []string{"*fmt.wrapError", "*errors.errorString"},
+	}).Error("latency: 1ms")
+	New().WithField("callback", func() {}).Warn("invalid field")
+	New().WithField("key", "logrus_error").Warn("not a logrus_error")
+	New().Debug("not logged")
+
+	stats := metrics.Stats()
+	assert.Equal(t, map[string]uint64{"info": 4, "error": 1, "warning": 2}, stats.Levels)
+	assert.Equal(t, map[string]uint64{"/users/:id": 2, "/orders": 2, OtherRoute: 1}, stats.Routes)
+	assert.Equal(t, map[string]uint64{"*errors.errorString": 1}, stats.ErrorTypes)
+	assert.Equal(t, uint64(1), stats.LogrusErrors)
+	assert.Equal(t, uint64(7), stats.Format.Count)
+	assert.Equal(t, uint64(7), stats.Writes["buffer"].Count)
+	assert.Equal(t, map[string]uint64{"async": 3}, stats.Dropped)
+	assert.Equal(t, map[string]uint64{"async": 2}, stats.Sampled)
+
+	var expvar LogMetricsStats
+	assert.Nil(t, json.Unmarshal([]byte(metrics.String()), &expvar))
+	assert.Equal(t, stats.Levels, expvar.Levels)
+
+	assert.Len(t, stats.Format.Buckets, len(DefaultLogMetricsBuckets))
+	assert.Equal(t, stats.Format.Count, stats.Format.Buckets[.1])
+
+	// A failing formatter is counted.
+	New(WithFormatter(metrics.Formatter(failingFormatter{})))
+	New().Info("failed")
+	assert.Equal(t, uint64(1), metrics.Stats().FormatErrors)
+}
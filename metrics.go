@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxRoutes is the number of routes labelled before the other routes are labelled
+	// OtherRoute.
+	DefaultMaxRoutes = 500
+
+	// UnmatchedRoute is the route of the requests which matched no route, in the request logs and
+	// the metrics.
+	UnmatchedRoute = "unmatched"
+	// OtherRoute labels the requests of the routes over MaxRoutes.
+	OtherRoute = "other"
+)
+
+// Metrics records the RED (rate, errors, duration) metrics of the requests logged by the
+// middlewares. The Metrics of the github.com/trinhdaiphuc/logger/prometheus package implements
+// it with Prometheus, so the logger package does not depend on the Prometheus client.
+type Metrics interface {
+	// TrackHTTP counts an HTTP request in flight until the returned function is called.
+	TrackHTTP(method string) func()
+	// ObserveHTTP records an HTTP request answered with status. route is the route template
+	// matched by the router, UnmatchedRoute when the request matched no route.
+	ObserveHTTP(method, route string, status int, latency time.Duration)
+	// TrackGrpc counts a gRPC request in flight until the returned function is called.
+	TrackGrpc(service, method string) func()
+	// ObserveGrpc records a gRPC request answered with code.
+	ObserveGrpc(service, method, code string, latency time.Duration)
+}
+
+// noMetrics is the Metrics of the middlewares when none is configured.
+type noMetrics struct{}
+
+func (noMetrics) TrackHTTP(string) func()                           { return func() {} }
+func (noMetrics) ObserveHTTP(string, string, int, time.Duration)    {}
+func (noMetrics) TrackGrpc(string, string) func()                   { return func() {} }
+func (noMetrics) ObserveGrpc(string, string, string, time.Duration) {}
+
+// LabelLimiter bounds the values of a label, the values over max are labelled OtherRoute.
+// UnmatchedRoute is always labelled and is not counted in max.
+type LabelLimiter struct {
+	max    int
+	mu     sync.RWMutex
+	values map[string]struct{}
+}
+
+// NewLabelLimiter returns a LabelLimiter labelling max values.
+func NewLabelLimiter(max int) *LabelLimiter {
+	return &LabelLimiter{max: max, values: make(map[string]struct{})}
+}
+
+// Label returns value, or OtherRoute when max values are already labelled.
+func (l *LabelLimiter) Label(value string) string {
+	if value == UnmatchedRoute {
+		return value
+	}
+	l.mu.RLock()
+	_, ok := l.values[value]
+	l.mu.RUnlock()
+	if ok {
+		return value
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.values[value]; ok {
+		return value
+	}
+	if len(l.values) >= l.max {
+		return OtherRoute
+	}
+	l.values[value] = struct{}{}
+	return value
+}
+
+// routeLabel returns the route of a request, UnmatchedRoute when it matched no route.
+func routeLabel(route string) string {
+	if route == "" {
+		return UnmatchedRoute
+	}
+	return route
+}
+
+// splitFullMethod returns the service and the method of a gRPC full method
+// "/package.service/method".
+func splitFullMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
@@ -0,0 +1,239 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	pb "github.com/trinhdaiphuc/logger/proto/hello"
+	"google.golang.org/grpc"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics records the requests observed by the middlewares.
+type recordingMetrics struct {
+	mu       sync.Mutex
+	inFlight int
+	requests []string
+}
+
+func (m *recordingMetrics) TrackHTTP(method string) func() {
+	return m.track()
+}
+
+func (m *recordingMetrics) ObserveHTTP(method, route string, status int, latency time.Duration) {
+	m.observe(method, route, http.StatusText(status))
+}
+
+func (m *recordingMetrics) TrackGrpc(service, method string) func() {
+	return m.track()
+}
+
+func (m *recordingMetrics) ObserveGrpc(service, method, code string, latency time.Duration) {
+	m.observe(service, method, code)
+}
+
+func (m *recordingMetrics) track() func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight++
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.inFlight--
+	}
+}
+
+func (m *recordingMetrics) observe(labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, labels[0]+" "+labels[1]+" "+labels[2])
+}
+
+func TestMetricsHTTP(t *testing.T) {
+	New(WithFormatter(&JSONFormatter{}), WithOutput(&bytes.Buffer{}))
+	expect := []string{
+		"GET /users/:id OK",
+		"GET /users/:id OK",
+		"GET /users/:id Internal Server Error",
+	}
+	tests := []struct {
+		name    string
+		handler func(metrics Metrics) http.Handler
+	}{
+		{
+			name: "Test gin",
+			handler: func(metrics Metrics) http.Handler {
+				server := gin.New()
+				server.Use(GinMiddleware(ConfigGin{Metrics: metrics}))
+				server.GET("/users/:id", func(ctx *gin.Context) {
+					if ctx.Param("id") == "error" {
+						ctx.Status(http.StatusInternalServerError)
+						return
+					}
+					ctx.Status(http.StatusOK)
+				})
+				return server
+			},
+		},
+		{
+			name: "Test echo",
+			handler: func(metrics Metrics) http.Handler {
+				server := echo.New()
+				server.Use(EchoMiddleware(ConfigEcho{Metrics: metrics}))
+				server.GET("/users/:id", func(ctx echo.Context) error {
+					if ctx.Param("id") == "error" {
+						return echo.ErrInternalServerError
+					}
+					return ctx.NoContent(http.StatusOK)
+				})
+				return server
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &recordingMetrics{}
+			handler := tt.handler(metrics)
+			for _, path := range []string{"/users/1", "/users/2", "/users/error"} {
+				performRequest(handler, http.MethodGet, path)
+			}
+			assert.Equal(t, expect, metrics.requests)
+			assert.Equal(t, 0, metrics.inFlight)
+		})
+	}
+
+	t.Run("Test fiber", func(t *testing.T) {
+		metrics := &recordingMetrics{}
+		server := fiber.New()
+		server.Use(FiberMiddleware(ConfigFiber{Metrics: metrics}))
+		server.Get("/users/:id", func(ctx *fiber.Ctx) error {
+			if ctx.Params("id") == "error" {
+				return ctx.SendStatus(http.StatusInternalServerError)
+			}
+			return ctx.SendStatus(http.StatusOK)
+		})
+		for _, path := range []string{"/users/1", "/users/2", "/users/error"} {
+			_, err := performFiberRequest(server, http.MethodGet, path)
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, expect, metrics.requests)
+		assert.Equal(t, 0, metrics.inFlight)
+	})
+}
+
+func TestMetricsUnmatchedRoute(t *testing.T) {
+	New(WithFormatter(&JSONFormatter{}), WithOutput(&bytes.Buffer{}))
+	expect := []string{
+		"GET unmatched Not Found",
+		"GET /users/:id Not Found",
+	}
+	tests := []struct {
+		name    string
+		handler func(metrics Metrics) http.Handler
+	}{
+		{
+			name: "Test gin",
+			handler: func(metrics Metrics) http.Handler {
+				server := gin.New()
+				server.Use(GinMiddleware(ConfigGin{Metrics: metrics}))
+				server.GET("/users/:id", func(ctx *gin.Context) {
+					ctx.Status(http.StatusNotFound)
+				})
+				return server
+			},
+		},
+		{
+			name: "Test echo",
+			handler: func(metrics Metrics) http.Handler {
+				server := echo.New()
+				server.Use(EchoMiddleware(ConfigEcho{Metrics: metrics}))
+				server.GET("/users/:id", func(ctx echo.Context) error {
+					return echo.ErrNotFound
+				})
+				return server
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &recordingMetrics{}
+			handler := tt.handler(metrics)
+			for _, path := range []string{"/unknown", "/users/1"} {
+				performRequest(handler, http.MethodGet, path)
+			}
+			assert.Equal(t, expect, metrics.requests)
+		})
+	}
+
+	t.Run("Test fiber", func(t *testing.T) {
+		metrics := &recordingMetrics{}
+		server := fiber.New()
+		server.Use(FiberMiddleware(ConfigFiber{Metrics: metrics}))
+		server.Get("/users/:id", func(ctx *fiber.Ctx) error {
+			return fiber.ErrNotFound
+		})
+		for _, path := range []string{"/unknown", "/users/1"} {
+			_, err := performFiberRequest(server, http.MethodGet, path)
+			assert.Nil(t, err)
+		}
+		_, err := performFiberRequest(server, http.MethodPost, "/users/1")
+		assert.Nil(t, err)
+		assert.Equal(t, append(expect, "POST unmatched Method Not Allowed"), metrics.requests)
+	})
+}
+
+func TestMetricsGrpc(t *testing.T) {
+	New(WithFormatter(&JSONFormatter{}), WithOutput(&bytes.Buffer{}))
+	metrics := &recordingMetrics{}
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "", grpc.WithInsecure(), grpc.WithContextDialer(dialer(ConfigGrpc{Metrics: metrics})))
+	assert.Nil(t, err)
+	defer conn.Close()
+	client := pb.NewHelloServiceClient(conn)
+	for _, name := range []string{"world", ""} {
+		_, _ = client.Hello(ctx, &pb.HelloRequest{Name: name})
+	}
+
+	assert.Equal(t, []string{
+		"hello.HelloService Hello OK",
+		"hello.HelloService Hello InvalidArgument",
+	}, metrics.requests)
+	assert.Equal(t, 0, metrics.inFlight)
+}
+
+func TestSplitFullMethod(t *testing.T) {
+	service, method := splitFullMethod("/hello.HelloService/Hello")
+	assert.Equal(t, "hello.HelloService", service)
+	assert.Equal(t, "Hello", method)
+	service, method = splitFullMethod("Hello")
+	assert.Equal(t, "unknown", service)
+	assert.Equal(t, "Hello", method)
+}
+
+func TestLabelLimiter(t *testing.T) {
+	limiter := NewLabelLimiter(2)
+	assert.Equal(t, "/users/:id", limiter.Label("/users/:id"))
+	assert.Equal(t, "/orders", limiter.Label("/orders"))
+	assert.Equal(t, OtherRoute, limiter.Label("/items"))
+	assert.Equal(t, "/users/:id", limiter.Label("/users/:id"))
+	assert.Equal(t, UnmatchedRoute, limiter.Label(UnmatchedRoute))
+}
+
+func TestHasLogrusError(t *testing.T) {
+	for line, expected := range map[string]bool{
+		`{"logrus_error":"can not add field \"callback\"","msg":"a"}`: true,
+		`level=warning logrus_error="can not add field callback"`:     true,
+		`logrus_error="can not add field callback" msg=a`:             true,
+		`{"msg":"logrus_error"}`:                                      false,
+		`level=warning key=logrus_error`:                              false,
+	} {
+		assert.Equal(t, expected, hasLogrusError([]byte(line)), line)
+	}
+}
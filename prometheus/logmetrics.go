@@ -0,0 +1,85 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trinhdaiphuc/logger"
+)
+
+// ConfigLogMetrics defines the Prometheus metrics of a logger.LogMetrics.
+type ConfigLogMetrics struct {
+	// Namespace and Subsystem prefix the names of the metrics.
+	Namespace string
+	Subsystem string
+}
+
+// LogMetrics is a prometheus.Collector exporting the counters of a logger.LogMetrics.
+type LogMetrics struct {
+	metrics *logger.LogMetrics
+
+	levelsDesc       *prometheus.Desc
+	routesDesc       *prometheus.Desc
+	errorTypesDesc   *prometheus.Desc
+	logrusErrorsDesc *prometheus.Desc
+	formatErrorsDesc *prometheus.Desc
+	formatDesc       *prometheus.Desc
+	writeErrorsDesc  *prometheus.Desc
+	writesDesc       *prometheus.Desc
+	droppedDesc      *prometheus.Desc
+	sampledDesc      *prometheus.Desc
+}
+
+// NewLogMetrics returns the collector of metrics defined by config.
+func NewLogMetrics(metrics *logger.LogMetrics, config ConfigLogMetrics) *LogMetrics {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(config.Namespace, config.Subsystem, name), help, labels, nil)
+	}
+	return &LogMetrics{
+		metrics: metrics,
+
+		levelsDesc:       desc("log_lines_total", "Number of lines logged.", "level"),
+		routesDesc:       desc("log_route_lines_total", "Number of request lines logged.", "route"),
+		errorTypesDesc:   desc("log_error_lines_total", "Number of request lines logged with an error.", "error_type"),
+		logrusErrorsDesc: desc("log_logrus_errors_total", "Number of lines logged with invalid fields."),
+		formatErrorsDesc: desc("log_format_errors_total", "Number of lines which failed to be formatted."),
+		formatDesc:       desc("log_format_duration_seconds", "Duration of the formats of the lines."),
+		writeErrorsDesc:  desc("log_write_errors_total", "Number of lines which failed to be written.", "writer"),
+		writesDesc:       desc("log_write_duration_seconds", "Duration of the writes of the lines.", "writer"),
+		droppedDesc:      desc("log_dropped_lines_total", "Number of lines dropped.", "writer"),
+		sampledDesc:      desc("log_sampled_lines_total", "Number of lines dropped by sampling.", "writer"),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *LogMetrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		m.levelsDesc, m.routesDesc, m.errorTypesDesc, m.logrusErrorsDesc, m.formatErrorsDesc,
+		m.formatDesc, m.writeErrorsDesc, m.writesDesc, m.droppedDesc, m.sampledDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (m *LogMetrics) Collect(ch chan<- prometheus.Metric) {
+	counters := func(desc *prometheus.Desc, counts map[string]uint64) {
+		for label, count := range counts {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(count), label)
+		}
+	}
+	histogram := func(desc *prometheus.Desc, stats logger.LatencyStats, labels ...string) prometheus.Metric {
+		return prometheus.MustNewConstHistogram(desc, stats.Count, stats.Sum.Seconds(), stats.Buckets, labels...)
+	}
+	stats := m.metrics.Stats()
+	counters(m.levelsDesc, stats.Levels)
+	counters(m.routesDesc, stats.Routes)
+	counters(m.errorTypesDesc, stats.ErrorTypes)
+	counters(m.writeErrorsDesc, stats.WriteErrors)
+	counters(m.droppedDesc, stats.Dropped)
+	counters(m.sampledDesc, stats.Sampled)
+	ch <- prometheus.MustNewConstMetric(m.logrusErrorsDesc, prometheus.CounterValue, float64(stats.LogrusErrors))
+	ch <- prometheus.MustNewConstMetric(m.formatErrorsDesc, prometheus.CounterValue, float64(stats.FormatErrors))
+	ch <- histogram(m.formatDesc, stats.Format)
+	for name, writes := range stats.Writes {
+		ch <- histogram(m.writesDesc, writes, name)
+	}
+}
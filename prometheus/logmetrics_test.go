@@ -0,0 +1,62 @@
+package prometheus
+
+import (
+	"bytes"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/trinhdaiphuc/logger"
+	"os"
+	"strings"
+	"testing"
+)
+
+type dropCounter struct {
+	dropped, sampled uint64
+}
+
+func (c dropCounter) DropCounts() (dropped, sampled uint64) {
+	return c.dropped, c.sampled
+}
+
+func TestLogMetrics(t *testing.T) {
+	var (
+		buf     = &bytes.Buffer{}
+		metrics = logger.NewLogMetrics(logger.ConfigLogMetrics{})
+	)
+	logger.New(logger.WithLogMetrics(metrics), logger.WithOutput(metrics.Writer("buffer", buf)),
+		logger.WithFormatter(metrics.Formatter(&logger.JSONFormatter{})))
+	defer logger.New(logger.WithLogMetrics(nil), logger.WithOutput(os.Stderr))
+	metrics.Watch("async", dropCounter{dropped: 3, sampled: 2})
+
+	for _, route := range []string{"/users/:id", "/users/:id", "/orders"} {
+		logger.New().WithField(logger.RouteField, route).Info("latency: 1ms")
+	}
+	logger.New().WithFields(map[string]interface{}{
+		logger.RouteField:      "/orders",
+		logger.ErrorTypesField: []string{"*fmt.wrapError", "*errors.errorString"},
+	}).Error("latency: 1ms")
+
+	collector := NewLogMetrics(metrics, ConfigLogMetrics{Namespace: "app"})
+	registry := prometheus.NewPedanticRegistry()
+	assert.Nil(t, registry.Register(collector))
+	expect := `
+# HELP app_log_error_lines_total Number of request lines logged with an error.
+# TYPE app_log_error_lines_total counter
+app_log_error_lines_total{error_type="*errors.errorString"} 1
+# HELP app_log_lines_total Number of lines logged.
+# TYPE app_log_lines_total counter
+app_log_lines_total{level="error"} 1
+app_log_lines_total{level="info"} 3
+# HELP app_log_route_lines_total Number of request lines logged.
+# TYPE app_log_route_lines_total counter
+app_log_route_lines_total{route="/orders"} 2
+app_log_route_lines_total{route="/users/:id"} 2
+# HELP app_log_dropped_lines_total Number of lines dropped.
+# TYPE app_log_dropped_lines_total counter
+app_log_dropped_lines_total{writer="async"} 3
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expect),
+		"app_log_lines_total", "app_log_route_lines_total", "app_log_error_lines_total", "app_log_dropped_lines_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(collector, "app_log_format_duration_seconds", "app_log_write_duration_seconds"))
+}
@@ -0,0 +1,175 @@
+// Package prometheus exports the metrics of the logger package to Prometheus, so the logger
+// package does not depend on the Prometheus client.
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trinhdaiphuc/logger"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ConfigMetrics defines the Prometheus metrics of the middlewares.
+type ConfigMetrics struct {
+	// Namespace and Subsystem prefix the names of the metrics.
+	Namespace string
+	Subsystem string
+
+	// ConstLabels are the labels added to every metric, e.g. the service.
+	ConstLabels prometheus.Labels
+
+	// Buckets are the buckets of the duration histograms, in seconds. Default is
+	// prometheus.DefBuckets.
+	Buckets []float64
+
+	// MaxRoutes is the number of routes labelled, the requests of the other routes are labelled
+	// logger.OtherRoute so a router matching raw paths can't explode the cardinality. Default is
+	// logger.DefaultMaxRoutes.
+	MaxRoutes int
+}
+
+// OtherMethod labels the requests with a method which is not a standard HTTP method, as the
+// method is chosen by the client.
+const OtherMethod = "other"
+
+// httpMethods are the standard HTTP methods.
+var httpMethods = map[string]struct{}{
+	http.MethodGet:     {},
+	http.MethodHead:    {},
+	http.MethodPost:    {},
+	http.MethodPut:     {},
+	http.MethodPatch:   {},
+	http.MethodDelete:  {},
+	http.MethodConnect: {},
+	http.MethodOptions: {},
+	http.MethodTrace:   {},
+}
+
+// Metrics are the logger.Metrics of the middlewares as Prometheus metrics. The HTTP metrics are
+// labelled by method, route template and status class (2xx), the gRPC metrics by service,
+// method and code. The methods which are not standard HTTP methods are labelled OtherMethod. Register them with prometheus.MustRegister and set them in the Metrics of the
+// configs of the middlewares.
+type Metrics struct {
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	httpInFlight *prometheus.GaugeVec
+	grpcRequests *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+	grpcInFlight *prometheus.GaugeVec
+
+	routes *logger.LabelLimiter
+}
+
+// NewMetrics returns the metrics defined by config.
+func NewMetrics(config ConfigMetrics) *Metrics {
+	if config.Buckets == nil {
+		config.Buckets = prometheus.DefBuckets
+	}
+	if config.MaxRoutes <= 0 {
+		config.MaxRoutes = logger.DefaultMaxRoutes
+	}
+	opts := func(name, help string) prometheus.Opts {
+		return prometheus.Opts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        name,
+			Help:        help,
+			ConstLabels: config.ConstLabels,
+		}
+	}
+	histogramOpts := func(name, help string) prometheus.HistogramOpts {
+		return prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   config.Subsystem,
+			Name:        name,
+			Help:        help,
+			ConstLabels: config.ConstLabels,
+			Buckets:     config.Buckets,
+		}
+	}
+	return &Metrics{
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts(opts(
+			"http_requests_total", "Number of HTTP requests.")),
+			[]string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(histogramOpts(
+			"http_request_duration_seconds", "Duration of the HTTP requests."),
+			[]string{"method", "route", "status"}),
+		httpInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts(opts(
+			"http_requests_in_flight", "Number of HTTP requests being served.")),
+			[]string{"method"}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts(opts(
+			"grpc_server_handled_total", "Number of gRPC requests.")),
+			[]string{"service", "method", "code"}),
+		grpcDuration: prometheus.NewHistogramVec(histogramOpts(
+			"grpc_server_handling_seconds", "Duration of the gRPC requests."),
+			[]string{"service", "method", "code"}),
+		grpcInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts(opts(
+			"grpc_server_in_flight", "Number of gRPC requests being served.")),
+			[]string{"service", "method"}),
+		routes: logger.NewLabelLimiter(config.MaxRoutes),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.httpRequests.Describe(ch)
+	m.httpDuration.Describe(ch)
+	m.httpInFlight.Describe(ch)
+	m.grpcRequests.Describe(ch)
+	m.grpcDuration.Describe(ch)
+	m.grpcInFlight.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.httpRequests.Collect(ch)
+	m.httpDuration.Collect(ch)
+	m.httpInFlight.Collect(ch)
+	m.grpcRequests.Collect(ch)
+	m.grpcDuration.Collect(ch)
+	m.grpcInFlight.Collect(ch)
+}
+
+// TrackHTTP implements logger.Metrics.
+func (m *Metrics) TrackHTTP(method string) func() {
+	gauge := m.httpInFlight.WithLabelValues(methodLabel(method))
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// ObserveHTTP implements logger.Metrics.
+func (m *Metrics) ObserveHTTP(method, route string, status int, latency time.Duration) {
+	labels := []string{methodLabel(method), m.routes.Label(route), statusClass(status)}
+	m.httpRequests.WithLabelValues(labels...).Inc()
+	m.httpDuration.WithLabelValues(labels...).Observe(latency.Seconds())
+}
+
+// TrackGrpc implements logger.Metrics.
+func (m *Metrics) TrackGrpc(service, method string) func() {
+	gauge := m.grpcInFlight.WithLabelValues(service, method)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// ObserveGrpc implements logger.Metrics.
+func (m *Metrics) ObserveGrpc(service, method, code string, latency time.Duration) {
+	m.grpcRequests.WithLabelValues(service, method, code).Inc()
+	m.grpcDuration.WithLabelValues(service, method, code).Observe(latency.Seconds())
+}
+
+// methodLabel returns the label of an HTTP method, OtherMethod when it is not a standard method.
+func methodLabel(method string) string {
+	if _, ok := httpMethods[method]; ok {
+		return method
+	}
+	return OtherMethod
+}
+
+// statusClass returns the class of an HTTP status, e.g. "2xx".
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
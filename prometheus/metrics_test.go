@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/trinhdaiphuc/logger"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHTTP(t *testing.T) {
+	logger.New(logger.WithOutput(&bytes.Buffer{}))
+	defer logger.New(logger.WithOutput(os.Stderr))
+	metrics := NewMetrics(ConfigMetrics{})
+	server := gin.New()
+	server.Use(logger.GinMiddleware(logger.ConfigGin{Metrics: metrics}))
+	server.GET("/users/:id", func(ctx *gin.Context) {
+		if ctx.Param("id") == "error" {
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		ctx.Status(http.StatusOK)
+	})
+	for _, path := range []string{"/users/1", "/users/2", "/users/error"} {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	expect := `
+# HELP http_requests_total Number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/users/:id",status="2xx"} 2
+http_requests_total{method="GET",route="/users/:id",status="5xx"} 1
+`
+	assert.Nil(t, testutil.CollectAndCompare(metrics, strings.NewReader(expect), "http_requests_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics, "http_request_duration_seconds"))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.httpInFlight.WithLabelValues(http.MethodGet)))
+}
+
+func TestMetricsMaxRoutes(t *testing.T) {
+	metrics := NewMetrics(ConfigMetrics{MaxRoutes: 2, Buckets: []float64{0.1, 1}})
+	for _, route := range []string{"/a", "/b", "/c", "/a", logger.UnmatchedRoute} {
+		metrics.ObserveHTTP(http.MethodGet, route, http.StatusOK, 0)
+	}
+	for _, method := range []string{"FOO", "BAR"} {
+		metrics.TrackHTTP(method)()
+		metrics.ObserveHTTP(method, logger.UnmatchedRoute, http.StatusNotFound, 0)
+	}
+	expect := `
+# HELP http_requests_total Number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/a",status="2xx"} 2
+http_requests_total{method="GET",route="/b",status="2xx"} 1
+http_requests_total{method="GET",route="other",status="2xx"} 1
+http_requests_total{method="GET",route="unmatched",status="2xx"} 1
+http_requests_total{method="other",route="unmatched",status="4xx"} 2
+`
+	assert.Nil(t, testutil.CollectAndCompare(metrics, strings.NewReader(expect), "http_requests_total"))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics, "http_requests_in_flight"))
+	assert.Equal(t, "unknown", statusClass(0))
+}
+
+func TestMetricsGrpc(t *testing.T) {
+	metrics := NewMetrics(ConfigMetrics{Namespace: "app"})
+	registry := prometheus.NewPedanticRegistry()
+	assert.Nil(t, registry.Register(metrics))
+
+	for _, code := range []string{"OK", "InvalidArgument"} {
+		done := metrics.TrackGrpc("hello.HelloService", "Hello")
+		metrics.ObserveGrpc("hello.HelloService", "Hello", code, 0)
+		done()
+	}
+
+	expect := `
+# HELP app_grpc_server_handled_total Number of gRPC requests.
+# TYPE app_grpc_server_handled_total counter
+app_grpc_server_handled_total{code="InvalidArgument",method="Hello",service="hello.HelloService"} 1
+app_grpc_server_handled_total{code="OK",method="Hello",service="hello.HelloService"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expect), "app_grpc_server_handled_total"))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.grpcInFlight.WithLabelValues("hello.HelloService", "Hello")))
+}
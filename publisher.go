@@ -0,0 +1,377 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPublisherBatchSize     = 100
+	DefaultPublisherFlushInterval = time.Second
+	DefaultPublisherMaxRetries    = 3
+	DefaultPublisherMinBackoff    = 100 * time.Millisecond
+	DefaultPublisherMaxBackoff    = 5 * time.Second
+)
+
+// ErrPublisherClosed is returned by the writes of a closed PublisherWriter.
+var ErrPublisherClosed = errors.New("logger: publisher writer closed")
+
+// Message is a log line published to a queue.
+type Message struct {
+	// Key is the partition key of the message.
+	Key []byte
+	// Value is the log line.
+	Value []byte
+	// Time is the time the line was written.
+	Time time.Time
+}
+
+// Publisher publishes messages to a queue, partitioned by their key. Publish returns when the
+// messages are acknowledged by the queue. It returns a *PublishError when only some messages
+// failed to be delivered.
+type Publisher interface {
+	Publish(ctx context.Context, messages []Message) error
+}
+
+// PublishError is the error of the messages which failed to be delivered by a Publisher.
+type PublishError struct {
+	// Errors are the errors of the messages, nil for the messages which have been delivered.
+	Errors []error
+}
+
+func (e *PublishError) Error() string {
+	var (
+		failed int
+		last   error
+	)
+	for _, err := range e.Errors {
+		if err != nil {
+			failed, last = failed+1, err
+		}
+	}
+	return fmt.Sprintf("logger: %d of %d messages failed to be published: %v", failed, len(e.Errors), last)
+}
+
+// ConfigPublisher defines a PublisherWriter.
+type ConfigPublisher struct {
+	// KeyFields are the fields of the lines, joined by ':', which are the partition key of the
+	// messages, e.g. service or trace_id. Default is an empty key.
+	KeyFields []string
+
+	// BatchSize is the number of messages of a batch. Default is DefaultPublisherBatchSize.
+	BatchSize int
+
+	// MaxPending is the number of messages waiting to be published over which the lines are
+	// dropped. Default is 10 batches.
+	MaxPending int
+
+	// FlushInterval publishes the pending messages at least every FlushInterval. Default is
+	// DefaultPublisherFlushInterval.
+	FlushInterval time.Duration
+
+	// MaxRetries is the number of retries of the messages which failed to be delivered, a negative
+	// MaxRetries disables the retries. Default is DefaultPublisherMaxRetries.
+	MaxRetries int
+
+	// MinBackoff is the delay before the first retry, doubled at every retry up to MaxBackoff.
+	// Default is DefaultPublisherMinBackoff and DefaultPublisherMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnError is called with the messages which failed to be delivered after the retries.
+	OnError func(messages []Message, err error)
+}
+
+// PublisherStats are the counters of a PublisherWriter.
+type PublisherStats struct {
+	// Published is the number of messages acknowledged.
+	Published uint64 `json:"published"`
+	// Failed is the number of messages which failed to be delivered after the retries.
+	Failed uint64 `json:"failed"`
+	// Dropped is the number of lines dropped over MaxPending.
+	Dropped uint64 `json:"dropped"`
+	// Retries is the number of messages retried.
+	Retries uint64 `json:"retries"`
+}
+
+// PublisherWriter batches the lines written, formatted by a JSONFormatter, and publishes them
+// with a Publisher. Use it as the output of a Sink and Flush or Close it on shutdown. It is safe
+// for concurrent use.
+type PublisherWriter struct {
+	publisher Publisher
+	config    ConfigPublisher
+
+	mu      sync.Mutex
+	pending []Message
+	stats   PublisherStats
+	closed  bool
+
+	publish sync.Mutex
+	trigger chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewPublisherWriter returns a PublisherWriter publishing with publisher.
+func NewPublisherWriter(publisher Publisher, config ConfigPublisher) *PublisherWriter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultPublisherBatchSize
+	}
+	if config.MaxPending <= 0 {
+		config.MaxPending = 10 * config.BatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultPublisherFlushInterval
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	} else if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultPublisherMaxRetries
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = DefaultPublisherMinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultPublisherMaxBackoff
+	}
+	w := &PublisherWriter{
+		publisher: publisher,
+		config:    config,
+		trigger:   make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write adds the line p to the pending messages, keyed by the KeyFields of the line.
+func (w *PublisherWriter) Write(p []byte) (int, error) {
+	value := make([]byte, len(p))
+	copy(value, p)
+	message := Message{Key: w.key(value), Value: value, Time: time.Now()}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrPublisherClosed
+	}
+	if len(w.pending) >= w.config.MaxPending {
+		w.stats.Dropped++
+		return len(p), nil
+	}
+	w.pending = append(w.pending, message)
+	if len(w.pending) >= w.config.BatchSize {
+		select {
+		case w.trigger <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// key returns the partition key of a json line.
+func (w *PublisherWriter) key(line []byte) []byte {
+	if len(w.config.KeyFields) == 0 {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return nil
+	}
+	values := make([]string, len(w.config.KeyFields))
+	for i, field := range w.config.KeyFields {
+		if value, ok := fields[field]; ok {
+			values[i] = fieldString(value)
+		}
+	}
+	return []byte(strings.Join(values, ":"))
+}
+
+func (w *PublisherWriter) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.trigger:
+		case <-w.done:
+			return
+		}
+		_ = w.Flush(context.Background())
+	}
+}
+
+// Flush publishes the pending messages. It returns the error of the last batch which failed to
+// be delivered.
+func (w *PublisherWriter) Flush(ctx context.Context) error {
+	w.publish.Lock()
+	defer w.publish.Unlock()
+
+	var lastErr error
+	for {
+		w.mu.Lock()
+		n := len(w.pending)
+		if n > w.config.BatchSize {
+			n = w.config.BatchSize
+		}
+		batch := w.pending[:n:n]
+		w.pending = w.pending[n:]
+		w.mu.Unlock()
+		if len(batch) == 0 {
+			return lastErr
+		}
+		if err := w.publishBatch(ctx, batch); err != nil {
+			lastErr = err
+		}
+	}
+}
+
+// publishBatch publishes batch, retrying with an exponential backoff the messages which failed
+// to be delivered.
+func (w *PublisherWriter) publishBatch(ctx context.Context, batch []Message) error {
+	backoff := w.config.MinBackoff
+	for attempt := 0; ; attempt++ {
+		err := w.publisher.Publish(ctx, batch)
+		failed := failedMessages(batch, err)
+		w.addStats(PublisherStats{Published: uint64(len(batch) - len(failed))})
+		if err == nil {
+			return nil
+		}
+		if attempt >= w.config.MaxRetries || ctx.Err() != nil {
+			w.addStats(PublisherStats{Failed: uint64(len(failed))})
+			if w.config.OnError != nil {
+				w.config.OnError(failed, err)
+			}
+			return err
+		}
+		batch = failed
+		w.addStats(PublisherStats{Retries: uint64(len(batch))})
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		if backoff *= 2; backoff > w.config.MaxBackoff {
+			backoff = w.config.MaxBackoff
+		}
+	}
+}
+
+// failedMessages returns the messages of batch which failed to be delivered with err.
+func failedMessages(batch []Message, err error) []Message {
+	if err == nil {
+		return nil
+	}
+	var publishErr *PublishError
+	if !errors.As(err, &publishErr) || len(publishErr.Errors) != len(batch) {
+		return batch
+	}
+	var failed []Message
+	for i, messageErr := range publishErr.Errors {
+		if messageErr != nil {
+			failed = append(failed, batch[i])
+		}
+	}
+	return failed
+}
+
+// Close publishes the pending messages and stops the writer.
+func (w *PublisherWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.done)
+	<-w.stopped
+	return w.Flush(context.Background())
+}
+
+// Stats returns the counters of the writer.
+func (w *PublisherWriter) Stats() PublisherStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+// DropCounts implements DropCounter.
+func (w *PublisherWriter) DropCounts() (dropped, sampled uint64) {
+	return w.Stats().Dropped, 0
+}
+
+func (w *PublisherWriter) addStats(stats PublisherStats) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stats.Published += stats.Published
+	w.stats.Failed += stats.Failed
+	w.stats.Dropped += stats.Dropped
+	w.stats.Retries += stats.Retries
+}
+
+// MemoryPublisher is an in-memory Publisher with partitions, a stand-in for a queue in tests.
+// It is safe for concurrent use.
+type MemoryPublisher struct {
+	sync.Mutex
+	partitions [][]Message
+
+	// Fail returns the delivery error of a message, nil when it is delivered.
+	Fail func(message Message) error
+}
+
+// NewMemoryPublisher returns a MemoryPublisher with n partitions.
+func NewMemoryPublisher(n int) *MemoryPublisher {
+	if n <= 0 {
+		n = 1
+	}
+	return &MemoryPublisher{partitions: make([][]Message, n)}
+}
+
+// Publish appends the messages to the partition of the hash of their key.
+func (p *MemoryPublisher) Publish(ctx context.Context, messages []Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	var (
+		errs   = make([]error, len(messages))
+		failed bool
+	)
+	for i, message := range messages {
+		if p.Fail != nil {
+			if errs[i] = p.Fail(message); errs[i] != nil {
+				failed = true
+				continue
+			}
+		}
+		partition := p.Partition(message.Key)
+		p.partitions[partition] = append(p.partitions[partition], message)
+	}
+	if failed {
+		return &PublishError{Errors: errs}
+	}
+	return nil
+}
+
+// Partition returns the partition of key.
+func (p *MemoryPublisher) Partition(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(len(p.partitions)))
+}
+
+// Messages returns the messages published to a partition.
+func (p *MemoryPublisher) Messages(partition int) []Message {
+	p.Lock()
+	defer p.Unlock()
+	return append([]Message(nil), p.partitions[partition]...)
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPublisherWriterKeys(t *testing.T) {
+	publisher := NewMemoryPublisher(4)
+	w := NewPublisherWriter(publisher, ConfigPublisher{KeyFields: []string{"service", TraceIDField}, FlushInterval: time.Hour})
+	New(WithSinks(Sink{Output: w, Formatter: &JSONFormatter{}}))
+	defer New(WithSinks(), WithOutput(os.Stderr))
+
+	New().WithField("service", "hello").WithField(TraceIDField, "abc").Info("first")
+	New().WithField("service", "hello").WithField(TraceIDField, "abc").Info("second")
+	New().WithField("service", "world").Info("third")
+	assert.Nil(t, w.Close())
+
+	partition := publisher.Partition([]byte("hello:abc"))
+	messages := publisher.Messages(partition)
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, "hello:abc", string(messages[0].Key))
+		assert.Contains(t, string(messages[0].Value), `"msg":"first"`)
+		assert.Contains(t, string(messages[1].Value), `"msg":"second"`)
+	}
+	var keys []string
+	for _, message := range publisher.Messages(publisher.Partition([]byte("world:"))) {
+		keys = append(keys, string(message.Key))
+	}
+	assert.Contains(t, keys, "world:")
+	assert.Equal(t, PublisherStats{Published: 3}, w.Stats())
+
+	_, err := w.Write([]byte("{}"))
+	assert.Equal(t, ErrPublisherClosed, err)
+}
+
+func TestPublisherWriterBatches(t *testing.T) {
+	publisher := NewMemoryPublisher(1)
+	w := NewPublisherWriter(publisher, ConfigPublisher{BatchSize: 2, MaxPending: 3})
+	defer w.Close()
+	for i := 0; i < 2; i++ {
+		_, _ = w.Write([]byte(`{"msg":"a"}`))
+	}
+	assert.Eventually(t, func() bool {
+		return len(publisher.Messages(0)) == 2
+	}, time.Second, time.Millisecond)
+
+	// Over MaxPending, the lines are dropped.
+	w = NewPublisherWriter(publisher, ConfigPublisher{BatchSize: 10, MaxPending: 1, FlushInterval: time.Hour})
+	_, _ = w.Write([]byte(`{"msg":"b"}`))
+	_, _ = w.Write([]byte(`{"msg":"c"}`))
+	assert.Nil(t, w.Close())
+	assert.Equal(t, PublisherStats{Published: 1, Dropped: 1}, w.Stats())
+}
+
+func TestPublisherWriterRetries(t *testing.T) {
+	var (
+		publisher = NewMemoryPublisher(1)
+		attempts  = map[string]int{}
+		errFailed = errors.New("not enough replicas")
+	)
+	// "b" fails twice, "c" always fails.
+	publisher.Fail = func(message Message) error {
+		value := string(message.Value)
+		attempts[value]++
+		if value == "c" || value == "b" && attempts[value] <= 2 {
+			return errFailed
+		}
+		return nil
+	}
+	var failed []Message
+	w := NewPublisherWriter(publisher, ConfigPublisher{
+		MaxRetries:    3,
+		MinBackoff:    time.Millisecond,
+		FlushInterval: time.Hour,
+		OnError: func(messages []Message, err error) {
+			failed = messages
+		},
+	})
+	defer w.Close()
+	for _, value := range []string{"a", "b", "c"} {
+		_, _ = w.Write([]byte(value))
+	}
+	err := w.Flush(context.Background())
+	var publishErr *PublishError
+	if assert.True(t, errors.As(err, &publishErr)) {
+		assert.Equal(t, []error{errFailed}, publishErr.Errors)
+	}
+	assert.EqualError(t, err, "logger: 1 of 1 messages failed to be published: not enough replicas")
+
+	var values []string
+	for _, message := range publisher.Messages(0) {
+		values = append(values, string(message.Value))
+	}
+	assert.Equal(t, []string{"a", "b"}, values)
+	assert.Equal(t, map[string]int{"a": 1, "b": 3, "c": 4}, attempts)
+	if assert.Len(t, failed, 1) {
+		assert.Equal(t, "c", string(failed[0].Value))
+	}
+	assert.Equal(t, PublisherStats{Published: 2, Failed: 1, Retries: 5}, w.Stats())
+}
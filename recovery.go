@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"runtime/debug"
+)
+
+const (
+	PanicField = "panic"
+	StackField = "stack"
+)
+
+// ConfigRecovery defines the recovery from the panics of the handlers.
+type ConfigRecovery struct {
+	// Enable recovers from the panics of the handlers. The request is logged at error level with
+	// the panic value and stack, and answered with status 500 (codes.Internal for gRPC).
+	Enable bool
+
+	// RePanic panics again with the recovered value once the request is logged at panic level,
+	// to keep an outer recovery middleware working.
+	RePanic bool
+}
+
+// panicInfo is a panic recovered from a handler.
+type panicInfo struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *panicInfo) Error() string {
+	return fmt.Sprintf("panic: %v", p.value)
+}
+
+// call calls next and recovers from its panic when the recovery is enabled.
+// http.ErrAbortHandler is never recovered.
+func (r ConfigRecovery) call(next func() error) (p *panicInfo, err error) {
+	if !r.Enable {
+		return nil, next()
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			p = &panicInfo{value: v, stack: debug.Stack()}
+		}
+	}()
+	return nil, next()
+}
+
+// recordPanic adds the recovered panic value and its stack to log.
+func (l *Log) recordPanic(schema Schema, p *panicInfo) {
+	l.WithFields(map[string]interface{}{
+		schema.Panic: fmt.Sprint(p.value),
+		schema.Stack: string(p.stack),
+	})
+}
+
+// logPanic logs at panic level without panicking.
+func (l *Log) logPanic(msg string) {
+	defer func() {
+		_ = recover()
+	}()
+	l.Panic(msg)
+}
+
+// finish logs the request line at level when it is enabled by the level of the log, at error
+// level when p has been recovered and at panic level when p has to be re-panicked.
+func (l *Log) finish(msg string, level logrus.Level, p *panicInfo, recovery ConfigRecovery) {
+	switch {
+	case p != nil && recovery.RePanic:
+		l.logPanic(msg)
+	case p != nil && level > logrus.ErrorLevel:
+		l.Error(msg)
+	case l.IsLevelEnabled(level):
+		l.Log(level, msg)
+	}
+}
@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	pb "github.com/trinhdaiphuc/logger/proto/hello"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func assertPanicLog(t *testing.T, buf *bytes.Buffer, level string) {
+	t.Logf("Log output %v", buf.String())
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	_, ok := data["STEP_1"]
+	assert.True(t, ok, `cannot found expected "STEP_1" field: %v`, data)
+	assert.Equal(t, "hello panic", data[PanicField])
+	assert.Contains(t, data[StackField], "_test.go")
+	assert.Equal(t, level, data[FieldKeyLevel])
+	if code, ok := data[CodeField]; ok {
+		assert.Equal(t, codes.Internal.String(), code)
+	} else {
+		assert.Equal(t, float64(http.StatusInternalServerError), data[StatusField])
+	}
+}
+
+func TestRecoveryMiddlewares(t *testing.T) {
+	recovery := ConfigRecovery{Enable: true}
+	tests := []struct {
+		name    string
+		handler func() http.Handler
+	}{
+		{
+			name: "Gin",
+			handler: func() http.Handler {
+				server := gin.New()
+				server.Use(GinMiddleware(ConfigGin{Recovery: recovery}))
+				server.GET("/hello", func(ctx *gin.Context) {
+					GetLogger(ctx).AddLog("before panic")
+					panic("hello panic")
+				})
+				return server
+			},
+		},
+		{
+			name: "Echo",
+			handler: func() http.Handler {
+				server := echo.New()
+				server.Use(EchoMiddleware(ConfigEcho{Recovery: recovery}))
+				server.GET("/hello", func(ctx echo.Context) error {
+					GetLogger(ctx.Request().Context()).AddLog("before panic")
+					panic("hello panic")
+				})
+				return server
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+			w := performRequest(tt.handler(), "GET", "/hello")
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assertPanicLog(t, buf, "error")
+		})
+	}
+
+	t.Run("Fiber", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		server := fiber.New()
+		server.Use(FiberMiddleware(ConfigFiber{Recovery: recovery}))
+		server.Get("/hello", func(ctx *fiber.Ctx) error {
+			GetLogger(ctx.Context()).AddLog("before panic")
+			panic("hello panic")
+		})
+		w, err := server.Test(httptest.NewRequest("GET", "/hello", nil))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, w.StatusCode)
+		assertPanicLog(t, buf, "error")
+	})
+
+	t.Run("Grpc", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+		config := ConfigGrpc{Recovery: recovery}
+		conn, err := grpc.DialContext(context.Background(), "", grpc.WithInsecure(), grpc.WithContextDialer(dialer(config)))
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		_, err = pb.NewHelloServiceClient(conn).Hello(context.Background(), &pb.HelloRequest{Name: "panic"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assertPanicLog(t, buf, "error")
+	})
+}
+
+func TestRecoveryRePanic(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	server := gin.New()
+	server.Use(GinMiddleware(ConfigGin{Recovery: ConfigRecovery{Enable: true, RePanic: true}}))
+	server.GET("/hello", func(ctx *gin.Context) {
+		GetLogger(ctx).AddLog("before panic")
+		panic("hello panic")
+	})
+
+	assert.PanicsWithValue(t, "hello panic", func() {
+		performRequest(server, "GET", "/hello")
+	})
+	assertPanicLog(t, buf, "panic")
+}
+
+func TestRecoveryDisabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	server := gin.New()
+	server.Use(GinMiddleware(DefaultConfigGin))
+	server.GET("/hello", func(ctx *gin.Context) {
+		panic("hello panic")
+	})
+
+	assert.Panics(t, func() {
+		performRequest(server, "GET", "/hello")
+	})
+	assert.Empty(t, buf.String())
+}
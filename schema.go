@@ -0,0 +1,256 @@
+package logger
+
+// Schema defines the field names of the request log written by the middlewares.
+// Empty names are replaced by the names of DefaultSchema, except Latency which is only logged
+// as a field when it is set.
+type Schema struct {
+	ClientIP         string
+	RemoteAddr       string
+	RequestMethod    string
+	UserAgent        string
+	URI              string
+	Route            string
+	Path             string
+	Query            string
+	RequestSize      string
+	ResponseSize     string
+	Protocol         string
+	Scheme           string
+	Host             string
+	TLSVersion       string
+	TLSCipher        string
+	TLSServerName    string
+	TLSClientSubject string
+	AuthType         string
+	TraceID          string
+	SpanID           string
+	TraceSampled     string
+	Status           string
+	Errors           string
+	Start            string
+	End              string
+	Latency          string
+	Code             string
+	GrpcService      string
+	GrpcMethod       string
+	Authority        string
+	Deadline         string
+	ContextError     string
+	Request          string
+	Response         string
+	RequestHeaders   string
+	ResponseHeaders  string
+	QueryParams      string
+	Panic            string
+	Stack            string
+	ErrorTypes       string
+	ErrorChain       string
+	ErrorCode        string
+	ErrorMessage     string
+	ErrorDetails     string
+	ErrorReason      string
+	ErrorDomain      string
+	FieldErrors      string
+	RetryDelay       string
+}
+
+var (
+	// DefaultSchema is the schema used by the middlewares when none is configured.
+	DefaultSchema = Schema{
+		ClientIP:         ClientIPField,
+		RemoteAddr:       RemoteAddrField,
+		RequestMethod:    RequestMethodField,
+		UserAgent:        UserAgentField,
+		URI:              URIField,
+		Route:            RouteField,
+		Path:             PathField,
+		Query:            QueryField,
+		RequestSize:      RequestSizeField,
+		ResponseSize:     ResponseSizeField,
+		Protocol:         ProtocolField,
+		Scheme:           SchemeField,
+		Host:             HostField,
+		TLSVersion:       TLSVersionField,
+		TLSCipher:        TLSCipherField,
+		TLSServerName:    TLSServerNameField,
+		TLSClientSubject: TLSClientSubjectField,
+		AuthType:         AuthTypeField,
+		TraceID:          TraceIDField,
+		SpanID:           SpanIDField,
+		TraceSampled:     TraceSampledField,
+		Status:           StatusField,
+		Errors:           ErrorsField,
+		Start:            StartField,
+		End:              EndField,
+		Code:             CodeField,
+		GrpcService:      GrpcServiceField,
+		GrpcMethod:       GrpcMethodField,
+		Authority:        AuthorityField,
+		Deadline:         DeadlineField,
+		ContextError:     ContextErrorField,
+		Request:          RequestField,
+		Response:         ResponseField,
+		RequestHeaders:   RequestHeadersField,
+		ResponseHeaders:  ResponseHeadersField,
+		QueryParams:      QueryParamsField,
+		Panic:            PanicField,
+		Stack:            StackField,
+		ErrorTypes:       ErrorTypesField,
+		ErrorChain:       ErrorChainField,
+		ErrorCode:        ErrorCodeField,
+		ErrorMessage:     ErrorMessageField,
+		ErrorDetails:     ErrorDetailsField,
+		ErrorReason:      ErrorReasonField,
+		ErrorDomain:      ErrorDomainField,
+		FieldErrors:      FieldErrorsField,
+		RetryDelay:       RetryDelayField,
+	}
+
+	// SnakeCaseSchema is DefaultSchema with every field in snake case.
+	SnakeCaseSchema = Schema{
+		Status:  "status",
+		Errors:  "errors",
+		Latency: "latency",
+	}
+
+	// ECSSchema follows the Elastic Common Schema. The latency is logged in nanoseconds as
+	// event.duration.
+	ECSSchema = Schema{
+		ClientIP:         "client.ip",
+		RemoteAddr:       "source.address",
+		RequestMethod:    "http.request.method",
+		UserAgent:        "user_agent.original",
+		URI:              "url.original",
+		Path:             "url.path",
+		Query:            "url.query",
+		RequestSize:      "http.request.body.bytes",
+		ResponseSize:     "http.response.body.bytes",
+		Protocol:         "network.protocol",
+		Scheme:           "url.scheme",
+		Host:             "url.domain",
+		TLSVersion:       "tls.version",
+		TLSCipher:        "tls.cipher",
+		TLSServerName:    "tls.client.server_name",
+		TLSClientSubject: "tls.client.subject",
+		TraceID:          "trace.id",
+		SpanID:           "span.id",
+		Status:           "http.response.status_code",
+		Errors:           "error.message",
+		Start:            "event.start",
+		End:              "event.end",
+		Latency:          "event.duration",
+		Code:             "grpc.status_code",
+		Request:          "http.request.body.content",
+		Response:         "http.response.body.content",
+		RequestHeaders:   "http.request.headers",
+		ResponseHeaders:  "http.response.headers",
+		QueryParams:      "url.query_params",
+		Stack:            "error.stack_trace",
+		ErrorTypes:       "error.type",
+		ErrorCode:        "error.code",
+	}
+
+	// OTelSchema follows the OpenTelemetry semantic conventions for HTTP and RPC.
+	OTelSchema = Schema{
+		ClientIP:         "client.address",
+		RemoteAddr:       "network.peer.address",
+		RequestMethod:    "http.request.method",
+		UserAgent:        "user_agent.original",
+		URI:              "http.target",
+		Route:            "http.route",
+		Path:             "url.path",
+		RequestSize:      "http.request.body.size",
+		ResponseSize:     "http.response.body.size",
+		Protocol:         "network.protocol.version",
+		Scheme:           "url.scheme",
+		Host:             "server.address",
+		TLSVersion:       "tls.protocol.version",
+		TLSCipher:        "tls.cipher",
+		TLSServerName:    "tls.client.server_name",
+		TLSClientSubject: "tls.client.subject",
+		Status:           "http.response.status_code",
+		Errors:           "exception.message",
+		Code:             "rpc.grpc.status_code",
+		GrpcService:      "rpc.service",
+		GrpcMethod:       "rpc.method",
+		RequestHeaders:   "http.request.header",
+		ResponseHeaders:  "http.response.header",
+		QueryParams:      "url.query",
+		Stack:            "exception.stacktrace",
+		ErrorTypes:       "exception.type",
+	}
+
+	// GCPSchema follows the Google Cloud Logging httpRequest object. The fields are logged with
+	// dotted names, use a formatter which nests them to get a httpRequest object.
+	GCPSchema = Schema{
+		ClientIP:      "httpRequest.remoteIp",
+		RequestMethod: "httpRequest.requestMethod",
+		UserAgent:     "httpRequest.userAgent",
+		URI:           "httpRequest.requestUrl",
+		RequestSize:   "httpRequest.requestSize",
+		ResponseSize:  "httpRequest.responseSize",
+		Protocol:      "httpRequest.protocol",
+		Status:        "httpRequest.status",
+		Errors:        "error",
+		Latency:       "httpRequest.latency",
+		Stack:         "stack_trace",
+	}
+)
+
+// withDefaults returns the schema with empty field names replaced by DefaultSchema.
+func (s Schema) withDefaults() Schema {
+	fill := func(name *string, def string) {
+		if *name == "" {
+			*name = def
+		}
+	}
+	fill(&s.ClientIP, DefaultSchema.ClientIP)
+	fill(&s.RemoteAddr, DefaultSchema.RemoteAddr)
+	fill(&s.RequestMethod, DefaultSchema.RequestMethod)
+	fill(&s.UserAgent, DefaultSchema.UserAgent)
+	fill(&s.URI, DefaultSchema.URI)
+	fill(&s.Route, DefaultSchema.Route)
+	fill(&s.Path, DefaultSchema.Path)
+	fill(&s.Query, DefaultSchema.Query)
+	fill(&s.RequestSize, DefaultSchema.RequestSize)
+	fill(&s.ResponseSize, DefaultSchema.ResponseSize)
+	fill(&s.Protocol, DefaultSchema.Protocol)
+	fill(&s.Scheme, DefaultSchema.Scheme)
+	fill(&s.Host, DefaultSchema.Host)
+	fill(&s.TLSVersion, DefaultSchema.TLSVersion)
+	fill(&s.TLSCipher, DefaultSchema.TLSCipher)
+	fill(&s.TLSServerName, DefaultSchema.TLSServerName)
+	fill(&s.TLSClientSubject, DefaultSchema.TLSClientSubject)
+	fill(&s.AuthType, DefaultSchema.AuthType)
+	fill(&s.TraceID, DefaultSchema.TraceID)
+	fill(&s.SpanID, DefaultSchema.SpanID)
+	fill(&s.TraceSampled, DefaultSchema.TraceSampled)
+	fill(&s.Status, DefaultSchema.Status)
+	fill(&s.Errors, DefaultSchema.Errors)
+	fill(&s.Start, DefaultSchema.Start)
+	fill(&s.End, DefaultSchema.End)
+	fill(&s.Latency, DefaultSchema.Latency)
+	fill(&s.Code, DefaultSchema.Code)
+	fill(&s.GrpcService, DefaultSchema.GrpcService)
+	fill(&s.GrpcMethod, DefaultSchema.GrpcMethod)
+	fill(&s.Authority, DefaultSchema.Authority)
+	fill(&s.Deadline, DefaultSchema.Deadline)
+	fill(&s.ContextError, DefaultSchema.ContextError)
+	fill(&s.Request, DefaultSchema.Request)
+	fill(&s.Response, DefaultSchema.Response)
+	fill(&s.RequestHeaders, DefaultSchema.RequestHeaders)
+	fill(&s.ResponseHeaders, DefaultSchema.ResponseHeaders)
+	fill(&s.QueryParams, DefaultSchema.QueryParams)
+	fill(&s.Panic, DefaultSchema.Panic)
+	fill(&s.Stack, DefaultSchema.Stack)
+	fill(&s.ErrorTypes, DefaultSchema.ErrorTypes)
+	fill(&s.ErrorChain, DefaultSchema.ErrorChain)
+	fill(&s.ErrorCode, DefaultSchema.ErrorCode)
+	fill(&s.ErrorMessage, DefaultSchema.ErrorMessage)
+	fill(&s.ErrorDetails, DefaultSchema.ErrorDetails)
+	fill(&s.ErrorReason, DefaultSchema.ErrorReason)
+	fill(&s.ErrorDomain, DefaultSchema.ErrorDomain)
+	fill(&s.FieldErrors, DefaultSchema.FieldErrors)
+	fill(&s.RetryDelay, DefaultSchema.RetryDelay)
+	return s
+}
@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSchemaWithDefaults(t *testing.T) {
+	schema := Schema{Status: "status"}.withDefaults()
+	assert.Equal(t, "status", schema.Status)
+	assert.Equal(t, URIField, schema.URI)
+	assert.Equal(t, ErrorsField, schema.Errors)
+	assert.Equal(t, "", schema.Latency)
+	assert.Equal(t, DefaultSchema, Schema{}.withDefaults())
+}
+
+func TestSchemaProfiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema Schema
+		expect []string
+	}{
+		{
+			name:   "Test default schema",
+			schema: Schema{},
+			expect: []string{ClientIPField, RequestMethodField, UserAgentField, URIField, StatusField, EndField},
+		},
+		{
+			name:   "Test snake case schema",
+			schema: SnakeCaseSchema,
+			expect: []string{ClientIPField, URIField, "status", "latency"},
+		},
+		{
+			name:   "Test elastic common schema",
+			schema: ECSSchema,
+			expect: []string{"client.ip", "http.request.method", "url.original", "http.response.status_code", "event.duration"},
+		},
+		{
+			name:   "Test open telemetry schema",
+			schema: OTelSchema,
+			expect: []string{"client.address", "http.request.method", "http.target", "http.response.status_code"},
+		},
+		{
+			name:   "Test google cloud logging schema",
+			schema: GCPSchema,
+			expect: []string{"httpRequest.remoteIp", "httpRequest.requestMethod", "httpRequest.status", "httpRequest.latency"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+			server := gin.New()
+			server.Use(GinMiddleware(ConfigGin{Schema: tt.schema}))
+			server.GET("/hello", func(ctx *gin.Context) {
+				ctx.String(200, "hello")
+			})
+			performRequest(server, "GET", "/hello")
+
+			t.Logf("Log output %v", buf.String())
+			var data map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+				t.Error("unexpected error", err)
+			}
+			for _, field := range tt.expect {
+				_, ok := data[field]
+				assert.True(t, ok, `cannot found expected "%v" field: %v`, field, data)
+			}
+		})
+	}
+}
+
+func TestSchemaFiber(t *testing.T) {
+	buf := &bytes.Buffer{}
+	New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+	server := fiber.New()
+	server.Use(FiberMiddleware(ConfigFiber{Schema: ECSSchema}))
+	server.Get("/hello", func(ctx *fiber.Ctx) error {
+		return ctx.SendString("hello")
+	})
+	_, err := server.Test(httptest.NewRequest("GET", "/hello", nil))
+	assert.Nil(t, err)
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Error("unexpected error", err)
+	}
+	assert.Equal(t, "/hello", data["url.original"])
+	assert.Equal(t, float64(200), data["http.response.status_code"])
+	_, ok := data[StatusField]
+	assert.False(t, ok, `unexpected "%v" field: %v`, StatusField, data)
+}
+
+func TestSchemaRoute(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(path string)
+	}{
+		{
+			name: "Test gin",
+			handle: func(path string) {
+				server := gin.New()
+				server.Use(GinMiddleware(ConfigGin{}))
+				server.GET("/users/:id", func(ctx *gin.Context) {})
+				performRequest(server, "GET", path)
+			},
+		},
+		{
+			name: "Test echo",
+			handle: func(path string) {
+				server := echo.New()
+				server.Use(EchoMiddleware(ConfigEcho{}))
+				server.GET("/users/:id", func(ctx echo.Context) error { return nil })
+				performRequest(server, "GET", path)
+			},
+		},
+		{
+			name: "Test fiber",
+			handle: func(path string) {
+				server := fiber.New()
+				server.Use(FiberMiddleware(ConfigFiber{}))
+				server.Get("/users/:id", func(ctx *fiber.Ctx) error { return nil })
+				_, _ = server.Test(httptest.NewRequest("GET", path, nil))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			New(WithFormatter(&JSONFormatter{}), WithOutput(buf))
+			tt.handle("/users/42?fields=name&sort=asc")
+
+			data := decodeLog(t, buf)
+			assert.Equal(t, "/users/42?fields=name&sort=asc", data[URIField])
+			assert.Equal(t, "/users/:id", data[RouteField])
+			assert.Equal(t, "/users/42", data[PathField])
+			assert.Equal(t, "fields=name&sort=asc", data[QueryField])
+
+			buf.Reset()
+			tt.handle("/users/42")
+			data = decodeLog(t, buf)
+			_, ok := data[QueryField]
+			assert.False(t, ok, `unexpected "%v" field: %v`, QueryField, data)
+
+			buf.Reset()
+			tt.handle("/orders/42")
+			data = decodeLog(t, buf)
+			assert.Equal(t, UnmatchedRoute, data[RouteField])
+			assert.Equal(t, "/orders/42", data[PathField])
+			assert.Equal(t, float64(404), data[StatusField])
+		})
+	}
+}
@@ -0,0 +1,655 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ShipperFormat is the payload format of a Shipper.
+type ShipperFormat int
+
+const (
+	// ShipJSON posts the lines as a json array.
+	ShipJSON ShipperFormat = iota
+	// ShipLoki posts the lines to the Loki push API, in streams of the labels of the lines.
+	ShipLoki
+	// ShipElasticsearch posts the lines to the Elasticsearch _bulk API.
+	ShipElasticsearch
+)
+
+const (
+	DefaultShipperBatchSize     = 500
+	DefaultShipperFlushInterval = time.Second
+	DefaultShipperMaxRetries    = 3
+	DefaultShipperMinBackoff    = 100 * time.Millisecond
+	DefaultShipperMaxBackoff    = 5 * time.Second
+	DefaultShipperTimeout       = 10 * time.Second
+	DefaultShipperCloseTimeout  = 30 * time.Second
+	DefaultElasticsearchIndex   = "logs"
+	// DefaultLokiJob is the job label of the Loki streams without labels, which Loki rejects.
+	DefaultLokiJob = "logger"
+
+	// spillSuffix is the suffix of the batches spilled to disk.
+	spillSuffix = ".batch"
+)
+
+// ErrShipperClosed is returned by the writes of a closed Shipper.
+var ErrShipperClosed = errors.New("logger: shipper closed")
+
+// ConfigShipper defines a Shipper.
+type ConfigShipper struct {
+	// URL is the endpoint of the batches, e.g. http://loki:3100/loki/api/v1/push or
+	// http://elasticsearch:9200/_bulk.
+	URL string
+
+	// Format is the payload format. Default is ShipJSON.
+	Format ShipperFormat
+
+	// Labels are the fields of the lines used as Loki labels, e.g. service, route and level.
+	Labels []string
+
+	// StaticLabels are the Loki labels of every line. The lines without labels are labelled with
+	// job=DefaultLokiJob.
+	StaticLabels map[string]string
+
+	// Index is the Elasticsearch index. Default is DefaultElasticsearchIndex.
+	Index string
+
+	// Headers are added to the requests, e.g. Authorization.
+	Headers map[string]string
+
+	// Gzip compresses the requests.
+	Gzip bool
+
+	// BatchSize is the number of lines of a batch. Default is DefaultShipperBatchSize.
+	BatchSize int
+
+	// MaxPending is the number of lines waiting to be shipped over which the lines are dropped.
+	// Default is 10 batches.
+	MaxPending int
+
+	// FlushInterval ships the pending lines at least every FlushInterval. Default is DefaultShipperFlushInterval.
+	FlushInterval time.Duration
+
+	// MaxRetries is the number of retries of a batch, a negative MaxRetries disables the retries.
+	// Default is DefaultShipperMaxRetries.
+	MaxRetries int
+
+	// MinBackoff is the delay before the first retry, doubled at every retry up to MaxBackoff.
+	// Default is DefaultShipperMinBackoff and DefaultShipperMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// SpillDir is the directory where the batches which failed to be shipped are written, they
+	// are shipped again before the next batches. Default drops them.
+	SpillDir string
+
+	// Client is the http client of the requests. Default is a client with Timeout.
+	Client *http.Client
+
+	// Timeout is the timeout of the requests of the default client. Default is
+	// DefaultShipperTimeout.
+	Timeout time.Duration
+
+	// CloseTimeout is the time Close waits for the pending lines to be shipped, the lines which
+	// are not shipped by then are spilled or dropped. Default is DefaultShipperCloseTimeout.
+	CloseTimeout time.Duration
+}
+
+// ShipperStats are the counters of a Shipper.
+type ShipperStats struct {
+	// Shipped is the number of lines shipped.
+	Shipped uint64 `json:"shipped"`
+	// Dropped is the number of lines dropped, over MaxPending or rejected by the endpoint,
+	// including the Elasticsearch bulk items rejected.
+	Dropped uint64 `json:"dropped"`
+	// Spilled is the number of lines spilled to disk.
+	Spilled uint64 `json:"spilled"`
+	// Retries is the number of requests retried.
+	Retries uint64 `json:"retries"`
+}
+
+// Shipper batches the lines written, formatted by a JSONFormatter, and posts them to a log
+// backend. Use it as the output of a Sink and Flush or Close it on shutdown. It is safe for
+// concurrent use.
+type Shipper struct {
+	config ConfigShipper
+
+	mu      sync.Mutex
+	pending [][]byte
+	stats   ShipperStats
+	closed  bool
+
+	ship    sync.Mutex
+	trigger chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	// ctx is the context of the flushes of the background goroutine, cancelled by Close.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewShipper returns a Shipper posting to config.URL.
+func NewShipper(config ConfigShipper) (*Shipper, error) {
+	if config.URL == "" {
+		return nil, errors.New("logger: empty shipper url")
+	}
+	if config.Index == "" {
+		config.Index = DefaultElasticsearchIndex
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultShipperBatchSize
+	}
+	if config.MaxPending <= 0 {
+		config.MaxPending = 10 * config.BatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultShipperFlushInterval
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	} else if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultShipperMaxRetries
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = DefaultShipperMinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultShipperMaxBackoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultShipperTimeout
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: config.Timeout}
+	}
+	if config.CloseTimeout <= 0 {
+		config.CloseTimeout = DefaultShipperCloseTimeout
+	}
+	if config.SpillDir != "" {
+		if err := os.MkdirAll(config.SpillDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	s := &Shipper{
+		config:  config,
+		trigger: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run()
+	return s, nil
+}
+
+// Write adds a copy of the line p to the pending lines.
+func (s *Shipper) Write(p []byte) (int, error) {
+	line := bytes.TrimRight(p, "\n")
+	line = append(make([]byte, 0, len(line)), line...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, ErrShipperClosed
+	}
+	if len(s.pending) >= s.config.MaxPending {
+		s.stats.Dropped++
+		return len(p), nil
+	}
+	s.pending = append(s.pending, line)
+	if len(s.pending) >= s.config.BatchSize {
+		select {
+		case s.trigger <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func (s *Shipper) run() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.trigger:
+		case <-s.done:
+			return
+		}
+		_ = s.Flush(s.ctx)
+	}
+}
+
+// Flush ships the spilled batches and the pending lines. It returns the error of the last
+// batch which failed to be shipped.
+func (s *Shipper) Flush(ctx context.Context) error {
+	s.ship.Lock()
+	defer s.ship.Unlock()
+
+	if err := s.shipSpilled(ctx); err != nil {
+		return s.spillPending(err)
+	}
+	for {
+		s.mu.Lock()
+		n := len(s.pending)
+		if n > s.config.BatchSize {
+			n = s.config.BatchSize
+		}
+		batch := s.pending[:n:n]
+		s.pending = s.pending[n:]
+		s.mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		body, err := s.encode(batch)
+		if err != nil {
+			s.addStats(ShipperStats{Dropped: uint64(len(batch))})
+			return err
+		}
+		if failed, lines, err := s.post(ctx, body, len(batch)); err != nil {
+			s.spill(failed, lines, err)
+			return s.spillPending(err)
+		}
+	}
+}
+
+// Close ships the pending lines and stops the shipper, waiting at most CloseTimeout.
+func (s *Shipper) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.CloseTimeout)
+	defer cancel()
+	defer s.cancel()
+	close(s.done)
+	select {
+	case <-s.stopped:
+	case <-ctx.Done():
+		s.cancel()
+		<-s.stopped
+	}
+	return s.Flush(ctx)
+}
+
+// Stats returns the counters of the shipper.
+func (s *Shipper) Stats() ShipperStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// DropCounts implements DropCounter.
+func (s *Shipper) DropCounts() (dropped, sampled uint64) {
+	return s.Stats().Dropped, 0
+}
+
+func (s *Shipper) addStats(stats ShipperStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Shipped += stats.Shipped
+	s.stats.Dropped += stats.Dropped
+	s.stats.Spilled += stats.Spilled
+	s.stats.Retries += stats.Retries
+}
+
+// post sends the lines of body, retrying with an exponential backoff the network errors, the 429
+// and 5xx responses and the Elasticsearch bulk items which failed with these statuses. The lines
+// shipped and the bulk items rejected are counted, the body and the number of the lines which
+// failed to be shipped are returned with the error.
+func (s *Shipper) post(ctx context.Context, body []byte, lines int) ([]byte, int, error) {
+	backoff := s.config.MinBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.send(ctx, body)
+		var bulk *bulkError
+		if errors.As(err, &bulk) {
+			failed := len(bulk.retry)
+			s.addStats(ShipperStats{Shipped: uint64(lines - bulk.rejected - failed), Dropped: uint64(bulk.rejected)})
+			if failed == 0 {
+				return nil, 0, nil
+			}
+			body, lines = bytes.Join(bulk.retry, nil), failed
+		} else if err == nil {
+			s.addStats(ShipperStats{Shipped: uint64(lines)})
+			return nil, 0, nil
+		}
+		if !retry || attempt >= s.config.MaxRetries {
+			return body, lines, err
+		}
+		s.addStats(ShipperStats{Retries: 1})
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return body, lines, ctx.Err()
+		}
+		if backoff *= 2; backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+}
+
+// shipperError is a batch rejected by the endpoint, it is dropped instead of being spilled.
+type shipperError struct {
+	status int
+	body   string
+}
+
+func (e *shipperError) Error() string {
+	return fmt.Sprintf("logger: shipper endpoint returned %d: %s", e.status, e.body)
+}
+
+// retryable reports whether the endpoint may accept the batch later.
+func (e *shipperError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+// bulkError is an Elasticsearch bulk request of which items failed.
+type bulkError struct {
+	// rejected is the number of items rejected.
+	rejected int
+	// retry are the action and document lines of the items which may be accepted later.
+	retry [][]byte
+}
+
+func (e *bulkError) Error() string {
+	return fmt.Sprintf("logger: elasticsearch rejected %d bulk items and failed %d bulk items", e.rejected, len(e.retry))
+}
+
+// bulkResponse is the response of the Elasticsearch _bulk API, the items are keyed by their action.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+	} `json:"items"`
+}
+
+// bulkItemsError returns the bulkError of the failed items of a bulk response to body, nil when
+// every item succeeded or the response is not a bulk response.
+func bulkItemsError(body, respBody []byte) error {
+	var resp bulkResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil || !resp.Errors {
+		return nil
+	}
+	lines := bytes.SplitAfter(body, []byte("\n"))
+	if len(resp.Items) != len(lines)/2 {
+		return fmt.Errorf("logger: elasticsearch bulk response of %d items to %d items", len(resp.Items), len(lines)/2)
+	}
+	bulk := &bulkError{}
+	for i, item := range resp.Items {
+		for _, result := range item {
+			failed := &shipperError{status: result.Status}
+			switch {
+			case result.Status < 300:
+			case failed.retryable():
+				bulk.retry = append(bulk.retry, lines[2*i], lines[2*i+1])
+			default:
+				bulk.rejected++
+			}
+		}
+	}
+	bulk.retry = bulk.retry[:len(bulk.retry):len(bulk.retry)]
+	return bulk
+}
+
+// isRejected reports whether err is a batch rejected by the endpoint.
+func isRejected(err error) bool {
+	var rejected *shipperError
+	return errors.As(err, &rejected) && !rejected.retryable()
+}
+
+// send posts body once and reports whether it may be retried.
+func (s *Shipper) send(ctx context.Context, body []byte) (bool, error) {
+	var reader io.Reader = bytes.NewReader(body)
+	if s.config.Gzip {
+		compressed := &bytes.Buffer{}
+		gz := gzip.NewWriter(compressed)
+		if _, err := gz.Write(body); err != nil {
+			return false, err
+		}
+		if err := gz.Close(); err != nil {
+			return false, err
+		}
+		reader = compressed
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, reader)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.Format == ShipElasticsearch {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+	if s.config.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if s.config.Format != ShipElasticsearch {
+			return false, nil
+		}
+		// Elasticsearch answers 200 to a bulk request of which items failed.
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return true, err
+		}
+		err = bulkItemsError(body, respBody)
+		var bulk *bulkError
+		return errors.As(err, &bulk) && len(bulk.retry) > 0, err
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	rejected := &shipperError{status: resp.StatusCode, body: string(respBody)}
+	return rejected.retryable(), rejected
+}
+
+// spill writes a batch which failed to be shipped to SpillDir, or drops it.
+func (s *Shipper) spill(body []byte, lines int, err error) {
+	if s.config.SpillDir == "" || isRejected(err) {
+		s.addStats(ShipperStats{Dropped: uint64(lines)})
+		return
+	}
+	name := filepath.Join(s.config.SpillDir, fmt.Sprintf("%020d-%d%s", time.Now().UnixNano(), lines, spillSuffix))
+	if err := os.WriteFile(name, body, 0644); err != nil {
+		s.addStats(ShipperStats{Dropped: uint64(lines)})
+		return
+	}
+	s.addStats(ShipperStats{Spilled: uint64(lines)})
+}
+
+// spillPending spills the pending lines when the endpoint is down, so they are not lost when the
+// process stops.
+func (s *Shipper) spillPending(err error) error {
+	if s.config.SpillDir == "" {
+		return err
+	}
+	for {
+		s.mu.Lock()
+		n := len(s.pending)
+		if n > s.config.BatchSize {
+			n = s.config.BatchSize
+		}
+		batch := s.pending[:n:n]
+		s.pending = s.pending[n:]
+		s.mu.Unlock()
+		if len(batch) == 0 {
+			return err
+		}
+		if body, encodeErr := s.encode(batch); encodeErr == nil {
+			s.spill(body, len(batch), err)
+		}
+	}
+}
+
+// shipSpilled ships the spilled batches, oldest first.
+func (s *Shipper) shipSpilled(ctx context.Context) error {
+	if s.config.SpillDir == "" {
+		return nil
+	}
+	names, err := filepath.Glob(filepath.Join(s.config.SpillDir, "*"+spillSuffix))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		body, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		base := filepath.Base(name)
+		lines, _ := strconv.Atoi(strings.TrimSuffix(base[strings.LastIndex(base, "-")+1:], spillSuffix))
+		failed, failedLines, err := s.post(ctx, body, lines)
+		if err == nil {
+			os.Remove(name)
+			continue
+		}
+		if isRejected(err) {
+			os.Remove(name)
+			s.addStats(ShipperStats{Dropped: uint64(failedLines)})
+			continue
+		}
+		// Only the lines which failed to be shipped are spilled again.
+		if failedLines < lines {
+			os.Remove(name)
+			s.spill(failed, failedLines, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// encode returns the payload of a batch.
+func (s *Shipper) encode(batch [][]byte) ([]byte, error) {
+	switch s.config.Format {
+	case ShipLoki:
+		return s.encodeLoki(batch)
+	case ShipElasticsearch:
+		action, err := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": s.config.Index}})
+		if err != nil {
+			return nil, err
+		}
+		b := &bytes.Buffer{}
+		for _, line := range batch {
+			b.Write(action)
+			b.WriteByte('\n')
+			// The documents are compacted, the bulk items are split on the new lines.
+			if err := json.Compact(b, jsonLine(line)); err != nil {
+				return nil, err
+			}
+			b.WriteByte('\n')
+		}
+		return b.Bytes(), nil
+	default:
+		lines := make([]json.RawMessage, len(batch))
+		for i, line := range batch {
+			lines[i] = jsonLine(line)
+		}
+		return json.Marshal(lines)
+	}
+}
+
+// jsonLine returns line when it is json, the line as a json string otherwise.
+func jsonLine(line []byte) json.RawMessage {
+	if json.Valid(line) {
+		return line
+	}
+	s, _ := json.Marshal(string(line))
+	return s
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encodeLoki groups the lines in streams of their labels.
+func (s *Shipper) encodeLoki(batch [][]byte) ([]byte, error) {
+	var (
+		streams []*lokiStream
+		byKey   = make(map[string]*lokiStream)
+	)
+	for _, line := range batch {
+		var fields map[string]interface{}
+		_ = json.Unmarshal(line, &fields)
+
+		labels := make(map[string]string, len(s.config.StaticLabels)+len(s.config.Labels))
+		for name, value := range s.config.StaticLabels {
+			labels[lokiLabelName(name)] = value
+		}
+		for _, field := range s.config.Labels {
+			if value, ok := fields[field]; ok {
+				labels[lokiLabelName(field)] = fieldString(value)
+			}
+		}
+		if len(labels) == 0 {
+			labels["job"] = DefaultLokiJob
+		}
+		key := lokiStreamKey(labels)
+		stream, ok := byKey[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			byKey[key] = stream
+			streams = append(streams, stream)
+		}
+
+		t := time.Now()
+		if ts, ok := fields[logrus.FieldKeyTime].(string); ok {
+			if parsed, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				t = parsed
+			}
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(t.UnixNano(), 10), string(line)})
+	}
+	return json.Marshal(map[string]interface{}{"streams": streams})
+}
+
+func lokiStreamKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b := &strings.Builder{}
+	for _, name := range names {
+		fmt.Fprintf(b, "%s=%q,", name, labels[name])
+	}
+	return b.String()
+}
+
+// lokiLabelName returns a label name matching [a-zA-Z_][a-zA-Z0-9_]*.
+func lokiLabelName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
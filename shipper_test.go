@@ -0,0 +1,269 @@
+package logger
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// shipperServer records the bodies of the requests, failing the first failures requests with status.
+type shipperServer struct {
+	sync.Mutex
+	*httptest.Server
+	bodies   []string
+	headers  []http.Header
+	failures int
+	status   int
+}
+
+func newShipperServer(failures, status int) *shipperServer {
+	s := &shipperServer{failures: failures, status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+		if s.failures > 0 {
+			s.failures--
+			w.WriteHeader(s.status)
+			return
+		}
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			reader = gz
+		}
+		body, _ := io.ReadAll(reader)
+		s.bodies = append(s.bodies, string(body))
+		s.headers = append(s.headers, r.Header)
+	}))
+	return s
+}
+
+func (s *shipperServer) requests() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func TestShipperFormats(t *testing.T) {
+	lines := []string{
+		`{"level":"info","msg":"a","route":"/hello","time":"2022-08-01T10:00:00Z"}`,
+		`{"level":"error","msg":"b","route":"/hello","time":"2022-08-01T10:00:01Z"}`,
+		`{"level":"info","msg":"c","route":"/hello","time":"2022-08-01T10:00:02Z"}`,
+	}
+	tests := []struct {
+		name        string
+		config      ConfigShipper
+		contentType string
+		expect      string
+	}{
+		{
+			name:        "Test json",
+			config:      ConfigShipper{Format: ShipJSON},
+			contentType: "application/json",
+			expect:      "[" + strings.Join(lines, ",") + "]",
+		},
+		{
+			name:        "Test elasticsearch",
+			config:      ConfigShipper{Format: ShipElasticsearch, Index: "app"},
+			contentType: "application/x-ndjson",
+			expect: `{"index":{"_index":"app"}}` + "\n" + lines[0] + "\n" +
+				`{"index":{"_index":"app"}}` + "\n" + lines[1] + "\n" +
+				`{"index":{"_index":"app"}}` + "\n" + lines[2] + "\n",
+		},
+		{
+			name: "Test loki",
+			config: ConfigShipper{
+				Format:       ShipLoki,
+				Labels:       []string{"level", "route"},
+				StaticLabels: map[string]string{"service": "hello"},
+				Gzip:         true,
+			},
+			contentType: "application/json",
+			expect: `{"streams":[` +
+				`{"stream":{"level":"info","route":"/hello","service":"hello"},"values":[` +
+				`["1659348000000000000",` + quoteJSON(lines[0]) + `],["1659348002000000000",` + quoteJSON(lines[2]) + `]]},` +
+				`{"stream":{"level":"error","route":"/hello","service":"hello"},"values":[` +
+				`["1659348001000000000",` + quoteJSON(lines[1]) + `]]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newShipperServer(0, 0)
+			defer server.Close()
+			tt.config.URL = server.URL
+			tt.config.Headers = map[string]string{"Authorization": "Bearer token"}
+			tt.config.FlushInterval = time.Hour
+			shipper, err := NewShipper(tt.config)
+			assert.Nil(t, err)
+			for _, line := range lines {
+				_, err = shipper.Write([]byte(line + "\n"))
+				assert.Nil(t, err)
+			}
+			assert.Nil(t, shipper.Close())
+
+			requests := server.requests()
+			assert.Equal(t, []string{tt.expect}, requests)
+			assert.Equal(t, tt.contentType, server.headers[0].Get("Content-Type"))
+			assert.Equal(t, "Bearer token", server.headers[0].Get("Authorization"))
+			assert.Equal(t, ShipperStats{Shipped: 3}, shipper.Stats())
+		})
+	}
+}
+
+func quoteJSON(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func TestShipperBatches(t *testing.T) {
+	server := newShipperServer(0, 0)
+	defer server.Close()
+	shipper, err := NewShipper(ConfigShipper{URL: server.URL, BatchSize: 2, MaxPending: 4})
+	assert.Nil(t, err)
+	New(WithSinks(Sink{Output: shipper, Formatter: &JSONFormatter{}}))
+	defer New(WithSinks(), WithOutput(os.Stderr))
+
+	for i := 0; i < 2; i++ {
+		New().Info("line")
+	}
+	assert.Eventually(t, func() bool {
+		return len(server.requests()) == 1
+	}, time.Second, time.Millisecond)
+
+	assert.Nil(t, shipper.Close())
+	_, err = shipper.Write([]byte("{}"))
+	assert.Equal(t, ErrShipperClosed, err)
+}
+
+func TestShipperRetries(t *testing.T) {
+	server := newShipperServer(2, http.StatusServiceUnavailable)
+	defer server.Close()
+	shipper, err := NewShipper(ConfigShipper{URL: server.URL, MinBackoff: time.Millisecond, FlushInterval: time.Hour})
+	assert.Nil(t, err)
+	_, _ = shipper.Write([]byte(`{"msg":"a"}`))
+	assert.Nil(t, shipper.Flush(context.Background()))
+	assert.Equal(t, []string{`[{"msg":"a"}]`}, server.requests())
+	assert.Equal(t, ShipperStats{Shipped: 1, Retries: 2}, shipper.Stats())
+
+	// A rejected batch is not retried.
+	server.Lock()
+	server.failures, server.status = 1, http.StatusBadRequest
+	server.Unlock()
+	_, _ = shipper.Write([]byte(`{"msg":"b"}`))
+	assert.NotNil(t, shipper.Flush(context.Background()))
+	assert.Equal(t, ShipperStats{Shipped: 1, Retries: 2, Dropped: 1}, shipper.Stats())
+	assert.Nil(t, shipper.Close())
+}
+
+func TestShipperSpill(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		server = newShipperServer(1, http.StatusBadGateway)
+	)
+	defer server.Close()
+	shipper, err := NewShipper(ConfigShipper{URL: server.URL, MaxRetries: -1, SpillDir: dir, FlushInterval: time.Hour})
+	assert.Nil(t, err)
+	defer shipper.Close()
+
+	// The endpoint is down, the batch is spilled.
+	_, _ = shipper.Write([]byte(`{"msg":"a"}`))
+	_, _ = shipper.Write([]byte(`{"msg":"b"}`))
+	assert.NotNil(t, shipper.Flush(context.Background()))
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+spillSuffix))
+	assert.Len(t, files, 1)
+	assert.Equal(t, ShipperStats{Spilled: 2}, shipper.Stats())
+
+	// The endpoint is up, the spilled batch is shipped first.
+	_, _ = shipper.Write([]byte(`{"msg":"c"}`))
+	assert.Nil(t, shipper.Flush(context.Background()))
+	assert.Equal(t, []string{`[{"msg":"a"},{"msg":"b"}]`, `[{"msg":"c"}]`}, server.requests())
+	files, _ = filepath.Glob(filepath.Join(dir, "*"+spillSuffix))
+	assert.Empty(t, files)
+	assert.Equal(t, ShipperStats{Shipped: 3, Spilled: 2}, shipper.Stats())
+}
+
+func TestShipperCloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	shipper, err := NewShipper(ConfigShipper{URL: server.URL, BatchSize: 1, MaxRetries: -1, CloseTimeout: 50 * time.Millisecond})
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultShipperTimeout, shipper.config.Client.Timeout)
+
+	// The endpoint never answers, Close gives up after CloseTimeout.
+	_, _ = shipper.Write([]byte(`{"msg":"a"}`))
+	start := time.Now()
+	_ = shipper.Close()
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, ShipperStats{Dropped: 1}, shipper.Stats())
+}
+
+func TestShipperLokiDefaultLabel(t *testing.T) {
+	server := newShipperServer(0, 0)
+	defer server.Close()
+	shipper, err := NewShipper(ConfigShipper{URL: server.URL, Format: ShipLoki, FlushInterval: time.Hour})
+	assert.Nil(t, err)
+	_, _ = shipper.Write([]byte(`{"msg":"a","time":"2022-08-01T10:00:00Z"}`))
+	assert.Nil(t, shipper.Close())
+	assert.Equal(t, []string{`{"streams":[{"stream":{"job":"logger"},"values":[["1659348000000000000","{\"msg\":\"a\",\"time\":\"2022-08-01T10:00:00Z\"}"]]}]}`}, server.requests())
+}
+
+func TestShipperElasticsearchItems(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			// The document a is indexed, b is rejected and c is retried.
+			_, _ = io.WriteString(w, `{"errors":true,"items":[`+
+				`{"index":{"status":201}},`+
+				`{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}},`+
+				`{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"errors":false,"items":[{"index":{"status":201}}]}`)
+	}))
+	defer server.Close()
+	shipper, err := NewShipper(ConfigShipper{URL: server.URL, Format: ShipElasticsearch, MinBackoff: time.Millisecond, FlushInterval: time.Hour})
+	assert.Nil(t, err)
+	for _, line := range []string{`{"msg":"a"}`, `{"msg":"b"}`, `{"msg":"c"}`} {
+		_, _ = shipper.Write([]byte(line))
+	}
+	assert.Nil(t, shipper.Flush(context.Background()))
+
+	mu.Lock()
+	assert.Equal(t, []string{
+		"{\"index\":{\"_index\":\"logs\"}}\n{\"msg\":\"a\"}\n{\"index\":{\"_index\":\"logs\"}}\n{\"msg\":\"b\"}\n{\"index\":{\"_index\":\"logs\"}}\n{\"msg\":\"c\"}\n",
+		"{\"index\":{\"_index\":\"logs\"}}\n{\"msg\":\"c\"}\n",
+	}, bodies)
+	mu.Unlock()
+	assert.Equal(t, ShipperStats{Shipped: 2, Dropped: 1, Retries: 1}, shipper.Stats())
+	assert.Nil(t, shipper.Close())
+}
+
+func TestLokiLabelName(t *testing.T) {
+	assert.Equal(t, "http_route", lokiLabelName("http.route"))
+	assert.Equal(t, "_2xx", lokiLabelName("2xx"))
+}
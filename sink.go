@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+	"io"
+	"sync"
+)
+
+// Sink is an output of the logs with its own formatter, level and filter.
+type Sink struct {
+	// Output is the writer of the sink.
+	Output io.Writer
+
+	// Formatter formats the logs of the sink. Default is ConsoleFormatter when the output is a
+	// terminal, JSONFormatter otherwise.
+	Formatter Formatter
+
+	// Levels are the levels written to the sink, e.g. LevelsUpTo(logrus.ErrorLevel). Default
+	// writes every level.
+	Levels []logrus.Level
+
+	// Filter reports whether a log is written to the sink, e.g. from the route of the request.
+	Filter func(entry *logrus.Entry) bool
+}
+
+// sinkHook writes the logs of the standard logger to a sink.
+type sinkHook struct {
+	sync.Mutex
+	sink   Sink
+	levels []logrus.Level
+}
+
+func newSinkHook(sink Sink) *sinkHook {
+	if sink.Formatter == nil {
+		sink.Formatter = AutoFormatter(sink.Output)
+	}
+	levels := sink.Levels
+	if levels == nil {
+		levels = logrus.AllLevels
+	}
+	return &sinkHook{sink: sink, levels: levels}
+}
+
+// LevelsUpTo returns the levels as severe as level or more, from PanicLevel to level.
+func LevelsUpTo(level logrus.Level) []logrus.Level {
+	levels := make([]logrus.Level, 0, len(logrus.AllLevels))
+	for _, l := range logrus.AllLevels {
+		if l <= level {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+// discardFormatter is the formatter of the standard logger while the logs are written to sinks,
+// the sinks format the logs with their own formatters.
+type discardFormatter struct{}
+
+func (discardFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
+
+func (h *sinkHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+func (h *sinkHook) Fire(entry *logrus.Entry) error {
+	if h.sink.Filter != nil && !h.sink.Filter(entry) {
+		return nil
+	}
+	h.Lock()
+	defer h.Unlock()
+	line, err := h.sink.Formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.sink.Output.Write(line)
+	return err
+}
+
+// WithSinks writes the logs to every sink instead of the output of WithOutput, replacing the
+// sinks of a previous WithSinks. The logs are only formatted by the sinks, WithFormatter has no
+// effect until WithSinks without sinks removes the sinks and restores the output and the
+// formatter used before the sinks.
+func WithSinks(sinks ...Sink) Option {
+	return func() {
+		standard.Lock()
+		defer standard.Unlock()
+		logger := logrus.StandardLogger()
+		hooks := make(logrus.LevelHooks)
+		for level, levelHooks := range logger.Hooks {
+			for _, hook := range levelHooks {
+				if _, ok := hook.(*sinkHook); !ok {
+					hooks[level] = append(hooks[level], hook)
+				}
+			}
+		}
+		for _, sink := range sinks {
+			hook := newSinkHook(sink)
+			for _, level := range hook.Levels() {
+				hooks[level] = append(hooks[level], hook)
+			}
+		}
+		logger.ReplaceHooks(hooks)
+		switch {
+		case len(sinks) > 0 && !standard.sinks:
+			standard.unsunkOut, standard.unsunkFormatter = logger.Out, logger.Formatter
+			logger.SetOutput(io.Discard)
+			logger.SetFormatter(discardFormatter{})
+		case len(sinks) == 0 && standard.sinks:
+			logger.SetOutput(standard.unsunkOut)
+			logger.SetFormatter(standard.unsunkFormatter)
+			standard.unsunkOut, standard.unsunkFormatter = nil, nil
+		}
+		standard.sinks = len(sinks) > 0
+	}
+}
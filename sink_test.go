@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSinks(t *testing.T) {
+	var (
+		all    = &bytes.Buffer{}
+		errs   = &bytes.Buffer{}
+		admin  = &bytes.Buffer{}
+		output = &bytes.Buffer{}
+	)
+	New(WithOutput(output), WithSinks(
+		Sink{Output: all, Formatter: &JSONFormatter{}},
+		Sink{Output: errs, Formatter: &LogfmtFormatter{DisableTimestamp: true}, Levels: LevelsUpTo(logrus.ErrorLevel)},
+		Sink{Output: admin, Formatter: &JSONFormatter{}, Filter: func(entry *logrus.Entry) bool {
+			uri, _ := entry.Data[URIField].(string)
+			return strings.HasPrefix(uri, "/admin")
+		}},
+	))
+	defer New(WithSinks(), WithOutput(os.Stderr))
+
+	server := gin.New()
+	server.Use(GinMiddleware(ConfigGin{Formatter: &JSONFormatter{}}))
+	server.GET("/hello", func(ctx *gin.Context) {})
+	server.GET("/admin/users", func(ctx *gin.Context) {
+		ctx.Error(Errorf("forbidden"))
+		ctx.Status(http.StatusForbidden)
+	})
+	performRequest(server, "GET", "/hello")
+	performRequest(server, "GET", "/admin/users")
+
+	t.Logf("All sink %v", all.String())
+	assert.Equal(t, 2, strings.Count(all.String(), "\n"))
+	assert.Equal(t, 1, strings.Count(errs.String(), "\n"))
+	assert.True(t, strings.HasPrefix(errs.String(), "level=error "), "unexpected error sink: %v", errs.String())
+	data := decodeLog(t, admin)
+	assert.Equal(t, "/admin/users", data[URIField])
+	assert.Empty(t, output.String())
+
+	// The sinks are replaced by the next WithSinks.
+	New(WithSinks(Sink{Output: errs, Levels: LevelsUpTo(logrus.ErrorLevel)}))
+	all.Reset()
+	performRequest(server, "GET", "/hello")
+	assert.Empty(t, all.String())
+	assert.Len(t, logrus.StandardLogger().Hooks[logrus.ErrorLevel], 1)
+}
+
+func TestSinksRemove(t *testing.T) {
+	var (
+		output = &bytes.Buffer{}
+		sink   = &bytes.Buffer{}
+		next   = &bytes.Buffer{}
+	)
+	formatter := &JSONFormatter{}
+	New(WithFormatter(formatter), WithOutput(output), WithSinks(Sink{Output: sink, Formatter: &JSONFormatter{}}))
+	defer New(WithSinks(), WithOutput(os.Stderr))
+	logrus.Info("a")
+	assert.Empty(t, output.String())
+	assert.Equal(t, 1, strings.Count(sink.String(), "\n"))
+
+	// The output and the formatter used before the sinks are restored.
+	New(WithSinks())
+	logrus.Info("b")
+	assert.Equal(t, 1, strings.Count(sink.String(), "\n"))
+	data := decodeLog(t, output)
+	assert.Equal(t, "b", data["msg"])
+	assert.Equal(t, formatter, logrus.StandardLogger().Formatter)
+
+	// The output set while the sinks are used is restored.
+	New(WithSinks(Sink{Output: sink, Formatter: &JSONFormatter{}}), WithOutput(next))
+	logrus.Info("c")
+	assert.Empty(t, next.String())
+	New(WithSinks())
+	logrus.Info("d")
+	data = decodeLog(t, next)
+	assert.Equal(t, "d", data["msg"])
+}
+
+func TestSinkLevels(t *testing.T) {
+	assert.Equal(t, []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}, LevelsUpTo(logrus.ErrorLevel))
+
+	var (
+		panics = &bytes.Buffer{}
+		all    = &bytes.Buffer{}
+	)
+	New(WithSinks(
+		Sink{Output: panics, Formatter: &JSONFormatter{}, Levels: []logrus.Level{logrus.PanicLevel}},
+		Sink{Output: all, Formatter: &JSONFormatter{}},
+	))
+	defer New(WithSinks(), WithOutput(os.Stderr))
+
+	logger := New()
+	logger.Error("error")
+	logger.logPanic("panic")
+	assert.Equal(t, 2, strings.Count(all.String(), "\n"))
+	data := decodeLog(t, panics)
+	assert.Equal(t, "panic", data["msg"])
+}
+
+// countingFormatter counts the logs it formats.
+type countingFormatter struct {
+	JSONFormatter
+	count int
+}
+
+func (f *countingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.count++
+	return f.JSONFormatter.Format(entry)
+}
+
+func TestSinksFormatOnce(t *testing.T) {
+	var (
+		sink       = &countingFormatter{}
+		middleware = &countingFormatter{}
+	)
+	formatter := &JSONFormatter{}
+	New(WithFormatter(formatter), WithSinks(Sink{Output: &bytes.Buffer{}, Formatter: sink}))
+	defer New(WithSinks(), WithOutput(os.Stderr))
+
+	server := gin.New()
+	server.Use(GinMiddleware(ConfigGin{Formatter: middleware}))
+	server.GET("/hello", func(ctx *gin.Context) {})
+	performRequest(server, "GET", "/hello")
+	assert.Equal(t, 1, sink.count)
+	assert.Equal(t, 0, middleware.count)
+
+	New(WithSinks())
+	assert.Equal(t, formatter, logrus.StandardLogger().Formatter)
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"encoding/json"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// statusFields returns the message and the details of a gRPC status. The details are decoded as
+// their protojson objects, and the reason and the domain of an ErrorInfo, the field violations of
+// a BadRequest and the delay of a RetryInfo are added as their own fields so they are searchable.
+func statusFields(schema Schema, s *status.Status) map[string]interface{} {
+	fields := map[string]interface{}{
+		schema.ErrorMessage: s.Message(),
+	}
+	details := s.Proto().GetDetails()
+	if len(details) == 0 {
+		return fields
+	}
+	decoded := make([]interface{}, 0, len(details))
+	var violations []map[string]string
+	for _, detail := range details {
+		decoded = append(decoded, decodeDetail(detail))
+		message, err := detail.UnmarshalNew()
+		if err != nil {
+			continue
+		}
+		switch d := message.(type) {
+		case *errdetails.ErrorInfo:
+			fields[schema.ErrorReason] = d.GetReason()
+			fields[schema.ErrorDomain] = d.GetDomain()
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				violations = append(violations, map[string]string{"field": v.GetField(), "description": v.GetDescription()})
+			}
+		case *errdetails.RetryInfo:
+			if d.GetRetryDelay() != nil {
+				fields[schema.RetryDelay] = d.GetRetryDelay().AsDuration()
+			}
+		}
+	}
+	fields[schema.ErrorDetails] = decoded
+	if len(violations) > 0 {
+		fields[schema.FieldErrors] = violations
+	}
+	return fields
+}
+
+// decodeDetail returns the protojson object of a status detail with its "@type", only the type
+// when the type of the detail is not registered.
+func decodeDetail(detail *anypb.Any) interface{} {
+	unknown := map[string]interface{}{"@type": detail.GetTypeUrl()}
+	b, err := protojson.Marshal(detail)
+	if err != nil {
+		return unknown
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return unknown
+	}
+	return decoded
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"testing"
+	"time"
+)
+
+func TestStatusFields(t *testing.T) {
+	schema := DefaultSchema
+	assert.Equal(t, map[string]interface{}{
+		ErrorMessageField: "not found",
+	}, statusFields(schema, status.New(codes.NotFound, "not found")))
+
+	s, err := status.New(codes.Unavailable, "unavailable").WithDetails(
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(2 * time.Second)},
+	)
+	assert.Nil(t, err)
+	proto := s.Proto()
+	proto.Details = append(proto.Details, &anypb.Any{TypeUrl: "type.googleapis.com/example.Unknown", Value: []byte("unknown")})
+	assert.Equal(t, map[string]interface{}{
+		ErrorMessageField: "unavailable",
+		ErrorDetailsField: []interface{}{
+			map[string]interface{}{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "2s"},
+			map[string]interface{}{"@type": "type.googleapis.com/example.Unknown"},
+		},
+		RetryDelayField: 2 * time.Second,
+	}, statusFields(schema, status.FromProto(proto)))
+
+	schema = Schema{RetryDelay: "retry.delay"}.withDefaults()
+	assert.Contains(t, statusFields(schema, status.FromProto(proto)), "retry.delay")
+}
@@ -0,0 +1,286 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SyslogFacility is the facility of the syslog messages.
+type SyslogFacility int
+
+const (
+	FacilityUser   SyslogFacility = 1
+	FacilityDaemon SyslogFacility = 3
+	FacilityLocal0 SyslogFacility = 16
+	FacilityLocal1 SyslogFacility = 17
+	FacilityLocal2 SyslogFacility = 18
+	FacilityLocal3 SyslogFacility = 19
+	FacilityLocal4 SyslogFacility = 20
+	FacilityLocal5 SyslogFacility = 21
+	FacilityLocal6 SyslogFacility = 22
+	FacilityLocal7 SyslogFacility = 23
+)
+
+const (
+	// DefaultSyslogAddress is the unix socket of the local syslog daemon.
+	DefaultSyslogAddress = "/dev/log"
+	// DefaultSyslogSDID is the id of the structured data element of the fields.
+	DefaultSyslogSDID = "fields@32473"
+
+	syslogNilValue     = "-"
+	syslogTimeFormat   = "2006-01-02T15:04:05.000000Z07:00"
+	syslogMaxParamName = 32
+	syslogMaxHostname  = 255
+	syslogMaxAppName   = 48
+	syslogMaxProcID    = 128
+	syslogVersion      = 1
+)
+
+// syslogSeverity returns the syslog severity of a logrus level.
+func syslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 1
+	case logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// SyslogFormatter formats the logs into RFC 5424 syslog messages, the fields being the parameters
+// of a structured data element. Use it with a SyslogWriter.
+type SyslogFormatter struct {
+	// Facility is the facility of the messages. Default is FacilityUser.
+	Facility SyslogFacility
+
+	// Hostname is the hostname of the messages. Default is os.Hostname.
+	Hostname string
+
+	// AppName is the application of the messages. Default is the name of the executable.
+	AppName string
+
+	// SDID is the id of the structured data element of the fields. Default is DefaultSyslogSDID.
+	SDID string
+
+	// Formatter formats the message of the logs, e.g. a JSONFormatter sends the whole request line.
+	// Default is the message of the log.
+	Formatter Formatter
+
+	once sync.Once
+}
+
+func (f *SyslogFormatter) init() {
+	if f.Facility == 0 {
+		f.Facility = FacilityUser
+	}
+	if f.Hostname == "" {
+		f.Hostname, _ = os.Hostname()
+	}
+	if f.AppName == "" {
+		f.AppName = filepath.Base(os.Args[0])
+	}
+	if f.SDID == "" {
+		f.SDID = DefaultSyslogSDID
+	}
+}
+
+// Format renders a single log entry
+func (f *SyslogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.once.Do(f.init)
+	msg, err := formatMessage(f.Formatter, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "<%d>%d %s %s %s %s %s ",
+		int(f.Facility)*8+syslogSeverity(entry.Level),
+		syslogVersion,
+		entry.Time.Format(syslogTimeFormat),
+		syslogHeader(f.Hostname, syslogMaxHostname),
+		syslogHeader(f.AppName, syslogMaxAppName),
+		syslogHeader(strconv.Itoa(os.Getpid()), syslogMaxProcID),
+		syslogNilValue,
+	)
+	if len(entry.Data) == 0 {
+		b.WriteString(syslogNilValue)
+	} else {
+		b.WriteByte('[')
+		b.WriteString(syslogParamName(f.SDID))
+		for _, key := range sortedKeys(entry.Data) {
+			b.WriteByte(' ')
+			b.WriteString(syslogParamName(key))
+			b.WriteString(`="`)
+			syslogParamValue(b, fieldString(entry.Data[key]))
+			b.WriteByte('"')
+		}
+		b.WriteByte(']')
+	}
+	if msg != "" {
+		b.WriteByte(' ')
+		b.WriteString(msg)
+	}
+	return b.Bytes(), nil
+}
+
+// formatMessage returns the message of entry, formatted by formatter when it is set.
+func formatMessage(formatter Formatter, entry *logrus.Entry) (string, error) {
+	if formatter == nil {
+		return entry.Message, nil
+	}
+	line, err := formatter.Format(entry)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\n"), nil
+}
+
+// syslogHeader returns a header field of printable ASCII characters.
+func syslogHeader(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return syslogNilValue
+	}
+	return s
+}
+
+// syslogParamName returns a structured data name of printable ASCII characters except '=', ' ',
+// ']' and '"'.
+func syslogParamName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > syslogMaxParamName {
+		s = s[:syslogMaxParamName]
+	}
+	return s
+}
+
+// syslogParamValue writes a structured data value with '"', '\' and ']' escaped.
+func syslogParamValue(b *bytes.Buffer, s string) {
+	for _, r := range s {
+		if r == '"' || r == '\\' || r == ']' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+}
+
+// SyslogWriter sends every log written to a syslog server. Over TCP the messages are framed with
+// their length (RFC 6587 octet counting), over a unix stream socket with a new line. It reconnects when a write fails. It is safe for
+// concurrent use.
+type SyslogWriter struct {
+	socket *socketWriter
+}
+
+// NewSyslogWriter connects to the syslog server at address over network: "unixgram" or "unix"
+// for a local daemon (DefaultSyslogAddress when address is empty), "udp" or "tcp".
+func NewSyslogWriter(network, address string) (*SyslogWriter, error) {
+	if network == "" {
+		network = "unixgram"
+	}
+	if address == "" {
+		address = DefaultSyslogAddress
+	}
+	socket := &socketWriter{network: network, address: address}
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		socket.frame = func(b []byte) []byte {
+			return append([]byte(strconv.Itoa(len(b))+" "), b...)
+		}
+	case "unix":
+		socket.frame = func(b []byte) []byte {
+			return append(b[:len(b):len(b)], '\n')
+		}
+	}
+	if err := socket.connect(); err != nil {
+		return nil, err
+	}
+	return &SyslogWriter{socket: socket}, nil
+}
+
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	return w.socket.Write(p)
+}
+
+// Close closes the connection.
+func (w *SyslogWriter) Close() error {
+	return w.socket.Close()
+}
+
+// socketWriter writes every log to a connection, reconnecting once when a write fails.
+type socketWriter struct {
+	sync.Mutex
+	network string
+	address string
+	frame   func([]byte) []byte
+	conn    net.Conn
+}
+
+func (w *socketWriter) connect() error {
+	conn, err := net.Dial(w.network, w.address)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *socketWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	msg := p
+	if w.frame != nil {
+		msg = w.frame(p)
+	}
+	if w.conn != nil {
+		if _, err := w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *socketWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func syslogEntry() *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(logrus.Fields{
+		URIField:    "/hello",
+		StatusField: 200,
+		"quote":     `say "hi"]`,
+		"bad key=":  "v",
+	})
+	entry.Time = time.Date(2022, 8, 1, 10, 0, 0, 123456000, time.UTC)
+	entry.Level = logrus.WarnLevel
+	entry.Message = "latency: 1ms"
+	return entry
+}
+
+func TestSyslogFormatter(t *testing.T) {
+	formatter := &SyslogFormatter{Facility: FacilityLocal0, Hostname: "host one", AppName: "app"}
+	line, err := formatter.Format(syslogEntry())
+	assert.Nil(t, err)
+	assert.Equal(t,
+		`<132>1 2022-08-01T10:00:00.123456Z host_one app `+strconv.Itoa(os.Getpid())+` - `+
+			`[fields@32473 Status="200" bad_key_="v" quote="say \"hi\"\]" uri="/hello"] latency: 1ms`,
+		string(line))
+
+	formatter = &SyslogFormatter{Hostname: "host", AppName: "app", Formatter: &LogfmtFormatter{DisableTimestamp: true}}
+	entry := syslogEntry()
+	entry.Data = logrus.Fields{}
+	line, err = formatter.Format(entry)
+	assert.Nil(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^<12>1 \S+ host app \d+ - - level=warning msg="latency: 1ms"$`), string(line))
+}
+
+func TestSyslogWriter(t *testing.T) {
+	formatter := &SyslogFormatter{Hostname: "host", AppName: "app"}
+
+	t.Run("Test unixgram", func(t *testing.T) {
+		dir, err := os.MkdirTemp("", "syslog")
+		assert.Nil(t, err)
+		defer os.RemoveAll(dir)
+		address := filepath.Join(dir, "log.sock")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: address, Net: "unixgram"})
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		w, err := NewSyslogWriter("unixgram", address)
+		assert.Nil(t, err)
+		defer w.Close()
+		line, _ := formatter.Format(syslogEntry())
+		_, err = w.Write(line)
+		assert.Nil(t, err)
+
+		buf := make([]byte, 4096)
+		n, err := conn.Read(buf)
+		assert.Nil(t, err)
+		assert.Equal(t, string(line), string(buf[:n]))
+	})
+
+	t.Run("Test udp", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		w, err := NewSyslogWriter("udp", conn.LocalAddr().String())
+		assert.Nil(t, err)
+		defer w.Close()
+		New(WithSinks(Sink{Output: w, Formatter: formatter}))
+		defer New(WithSinks(), WithOutput(os.Stderr))
+		New().WithField(URIField, "/hello").Warn("latency: 1ms")
+
+		buf := make([]byte, 4096)
+		n, _, err := conn.ReadFrom(buf)
+		assert.Nil(t, err)
+		assert.Regexp(t, `^<12>1 .* \[fields@32473 uri="/hello"\] latency: 1ms$`, string(buf[:n]))
+	})
+
+	t.Run("Test tcp", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer listener.Close()
+		messages := make(chan string, 2)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				var length int
+				if _, err := fmt.Fscanf(r, "%d ", &length); err != nil {
+					return
+				}
+				msg := make([]byte, length)
+				if _, err := io.ReadFull(r, msg); err != nil {
+					return
+				}
+				messages <- string(msg)
+			}
+		}()
+
+		w, err := NewSyslogWriter("tcp", listener.Addr().String())
+		assert.Nil(t, err)
+		defer w.Close()
+		for _, msg := range []string{"first", "second"} {
+			_, err = w.Write([]byte(msg))
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, "first", <-messages)
+		assert.Equal(t, "second", <-messages)
+	})
+}
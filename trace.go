@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HeaderTraceparent is the W3C Trace Context header, or lower case gRPC metadata key.
+	HeaderTraceparent = "Traceparent"
+	// HeaderCloudTraceContext is the trace context header of the Google Cloud load balancers.
+	HeaderCloudTraceContext = "X-Cloud-Trace-Context"
+)
+
+// traceFields returns the trace id, the span id and the sampled flag of a request read by get
+// from the W3C traceparent header, or else from the X-Cloud-Trace-Context header. It returns nil
+// when the request has no valid trace context.
+func traceFields(schema Schema, get func(name string) string) map[string]interface{} {
+	traceID, spanID, sampled, ok := parseTraceparent(get(HeaderTraceparent))
+	if !ok {
+		traceID, spanID, sampled, ok = parseCloudTraceContext(get(HeaderCloudTraceContext))
+	}
+	if !ok {
+		return nil
+	}
+	fields := map[string]interface{}{
+		schema.TraceID:      traceID,
+		schema.TraceSampled: sampled,
+	}
+	if spanID != "" {
+		fields[schema.SpanID] = spanID
+	}
+	return fields
+}
+
+// parseTraceparent parses a W3C traceparent header: version-traceid-parentid-flags, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func parseTraceparent(header string) (traceID, spanID string, sampled, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || parts[0] == "ff" || !isHexID(parts[0], 2) || parts[0] == "00" && len(parts) != 4 {
+		return "", "", false, false
+	}
+	if !isHexID(parts[1], 32) || !isHexID(parts[2], 16) || !isHexID(parts[3], 2) {
+		return "", "", false, false
+	}
+	flags, _ := hex.DecodeString(parts[3])
+	return parts[1], parts[2], flags[0]&1 == 1, true
+}
+
+// parseCloudTraceContext parses a X-Cloud-Trace-Context header: TRACE_ID/SPAN_ID;o=OPTIONS, the
+// span id and the options being optional. The decimal span id is returned in hex.
+func parseCloudTraceContext(header string) (traceID, spanID string, sampled, ok bool) {
+	value, options, _ := strings.Cut(strings.TrimSpace(header), ";")
+	traceID, span, _ := strings.Cut(value, "/")
+	traceID = strings.ToLower(traceID)
+	if !isHexID(traceID, 32) {
+		return "", "", false, false
+	}
+	if span != "" {
+		id, err := strconv.ParseUint(span, 10, 64)
+		if err != nil {
+			return "", "", false, false
+		}
+		if id != 0 {
+			spanID = fmt.Sprintf("%016x", id)
+		}
+	}
+	return traceID, spanID, options == "o=1", true
+}
+
+// isHexID reports whether s is a lower case hex id of n characters which is not only zeros.
+func isHexID(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	zero := true
+	for _, c := range s {
+		switch {
+		case c == '0':
+		case c >= '1' && c <= '9', c >= 'a' && c <= 'f':
+			zero = false
+		default:
+			return false
+		}
+	}
+	return !zero || n == 2
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTraceFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		expect  map[string]interface{}
+	}{
+		{
+			name:    "Test no header",
+			headers: map[string]string{},
+		},
+		{
+			name: "Test traceparent",
+			headers: map[string]string{
+				HeaderTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			},
+			expect: map[string]interface{}{
+				TraceIDField:      "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanIDField:       "00f067aa0ba902b7",
+				TraceSampledField: true,
+			},
+		},
+		{
+			name: "Test traceparent not sampled",
+			headers: map[string]string{
+				HeaderTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			},
+			expect: map[string]interface{}{
+				TraceIDField:      "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanIDField:       "00f067aa0ba902b7",
+				TraceSampledField: false,
+			},
+		},
+		{
+			name: "Test invalid traceparent",
+			headers: map[string]string{
+				HeaderTraceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			},
+		},
+		{
+			name: "Test cloud trace context",
+			headers: map[string]string{
+				HeaderCloudTraceContext: "105445AA7843BC8BF206B12000100000/1;o=1",
+			},
+			expect: map[string]interface{}{
+				TraceIDField:      "105445aa7843bc8bf206b12000100000",
+				SpanIDField:       "0000000000000001",
+				TraceSampledField: true,
+			},
+		},
+		{
+			name: "Test cloud trace context without span",
+			headers: map[string]string{
+				HeaderCloudTraceContext: "105445aa7843bc8bf206b12000100000",
+			},
+			expect: map[string]interface{}{
+				TraceIDField:      "105445aa7843bc8bf206b12000100000",
+				TraceSampledField: false,
+			},
+		},
+		{
+			name: "Test traceparent before cloud trace context",
+			headers: map[string]string{
+				HeaderTraceparent:       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				HeaderCloudTraceContext: "105445aa7843bc8bf206b12000100000/1;o=0",
+			},
+			expect: map[string]interface{}{
+				TraceIDField:      "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanIDField:       "00f067aa0ba902b7",
+				TraceSampledField: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := traceFields(DefaultSchema, func(name string) string { return tt.headers[name] })
+			if tt.expect == nil {
+				assert.Nil(t, fields)
+				return
+			}
+			assert.Equal(t, tt.expect, fields)
+		})
+	}
+}